@@ -3,12 +3,21 @@ package main
 import (
 	"github.com/QuangNV23062004/learning-go/internal/config"
 	"github.com/QuangNV23062004/learning-go/internal/database"
+	auditTransport "github.com/QuangNV23062004/learning-go/internal/pkg/audit/transport/http"
+	cartTransport "github.com/QuangNV23062004/learning-go/internal/pkg/carts/transport/http"
+	categoryTransport "github.com/QuangNV23062004/learning-go/internal/pkg/categories/transport/http"
 	orderTransport "github.com/QuangNV23062004/learning-go/internal/pkg/orders/transport/http"
+	permissionTransport "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/transport/http"
 	productTransport "github.com/QuangNV23062004/learning-go/internal/pkg/products/transport/http"
 	userTransport "github.com/QuangNV23062004/learning-go/internal/pkg/users/transport/http"
 
+	"context"
 	"log"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/QuangNV23062004/learning-go/internal/types"
 
@@ -18,6 +27,8 @@ import (
 	"github.com/gofiber/fiber/v3/middleware/compress"
 	"github.com/gofiber/fiber/v3/middleware/cors"
 	"github.com/gofiber/fiber/v3/middleware/helmet"
+	"github.com/gofiber/fiber/v3/middleware/recover"
+	"github.com/gofiber/fiber/v3/middleware/static"
 	"github.com/joho/godotenv"
 )
 
@@ -32,14 +43,21 @@ func main() {
 				Status:  status,
 				Success: false,
 				Error:   err.Error(),
+				Details: httpError.GetDetails(err),
 			})
-		}})
+		},
+		// image uploads are bigger than fiber's 4MB default
+		BodyLimit: config.GetEnvAsInt("BODY_LIMIT", 8*1024*1024),
+	})
+
+	// a panic in a handler fails that request instead of the whole server
+	app.Use(recover.New())
 
 	app.Use(helmet.New())
 
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"http://localhost:3000", config.GetEnv("FRONTEND_URL", "http://localhost:3000")},
-		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "If-Match"},
 		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 	}))
 
@@ -52,6 +70,13 @@ func main() {
 		})
 	})
 
+	//only the local driver serves files under /media, s3 falls back to the bucket url
+	storageDriver := config.GetEnv("STORAGE_DRIVER", "local")
+	storagePublicURL := ""
+	if storageDriver == "" || storageDriver == "local" {
+		storagePublicURL = config.GetEnv("SERVER_HOST", "http://localhost:2000") + "/media"
+	}
+
 	appConfig := config.AppConfig{
 
 		DB_CONFIG: config.DBConfig{
@@ -72,6 +97,8 @@ func main() {
 			RefreshExpiry: config.GetEnv("JWT_REFRESH_EXPIRY", "1h"),
 			VerifySecret:  config.GetEnv("JWT_VERIFY_SECRET", "5f4dcc3b5aa765d61d8327deb882cf99acd7aa40a1f9c7df48adfaf48c69e4c3203a6a17acc60638a7bdc9103d0a499997180cb8d8"),
 			VerifyExpiry:  config.GetEnv("JWT_VERIFY_EXPIRY", "30m"),
+			MfaSecret:     config.GetEnv("JWT_MFA_SECRET", "0b9f1c2e7d4a58e6f3b2c1d0a9e8f7c6b5a4d3e2f1c0b9a8e7d6c5b4a3f2e1d0c9b8a7"),
+			MfaExpiry:     config.GetEnv("JWT_MFA_EXPIRY", "5m"),
 		},
 
 		MAIL_CONFIG: config.MailConfig{
@@ -82,9 +109,43 @@ func main() {
 		},
 
 		SERVER_CONFIG: config.ServerConfig{
-			Host:    config.GetEnv("SERVER_HOST", "http://localhost:2000"),
-			Port:    config.GetEnvAsInt("PORT", 2000),
-			AppName: config.GetEnv("APP_NAME", "My Golang App"),
+			Host:        config.GetEnv("SERVER_HOST", "http://localhost:2000"),
+			Port:        config.GetEnvAsInt("PORT", 2000),
+			AppName:     config.GetEnv("APP_NAME", "My Golang App"),
+			FrontendURL: config.GetEnv("FRONTEND_URL", "http://localhost:3000"),
+		},
+
+		AUTH_CONFIG: config.AuthConfig{
+			ResetExpiry:       config.GetEnv("PASSWORD_RESET_EXPIRY", "30m"),
+			EmailChangeExpiry: config.GetEnv("EMAIL_CHANGE_EXPIRY", "24h"),
+
+			MaxLoginAttempts:   config.GetEnvAsInt("MAX_LOGIN_ATTEMPTS", 5),
+			MaxIPLoginAttempts: config.GetEnvAsInt("MAX_IP_LOGIN_ATTEMPTS", 20),
+			FailureWindow:      config.GetEnv("LOGIN_FAILURE_WINDOW", "15m"),
+			LockoutBase:        config.GetEnv("LOGIN_LOCKOUT_BASE", "1m"),
+			LockoutMax:         config.GetEnv("LOGIN_LOCKOUT_MAX", "1h"),
+		},
+
+		STORAGE_CONFIG: config.StorageConfig{
+			Driver:    storageDriver,
+			PublicURL: config.GetEnv("STORAGE_PUBLIC_URL", storagePublicURL),
+
+			LocalDir: config.GetEnv("STORAGE_LOCAL_DIR", "./uploads"),
+
+			S3Endpoint:  config.GetEnv("S3_ENDPOINT", "http://localhost:9000"),
+			S3Region:    config.GetEnv("S3_REGION", "us-east-1"),
+			S3Bucket:    config.GetEnv("S3_BUCKET", "products"),
+			S3AccessKey: config.GetEnv("S3_ACCESS_KEY", "minioadmin"),
+			S3SecretKey: config.GetEnv("S3_SECRET_KEY", "minioadmin"),
+
+			MaxImageSize:  config.GetEnvAsInt("MAX_IMAGE_SIZE", 5*1024*1024),
+			ThumbnailSize: config.GetEnvAsInt("THUMBNAIL_SIZE", 320),
+		},
+
+		ORDER_CONFIG: config.OrderConfig{
+			ReservationTTL: config.GetEnv("ORDER_RESERVATION_TTL", "30m"),
+			SweepInterval:  config.GetEnv("ORDER_SWEEP_INTERVAL", "1m"),
+			SweepBatchSize: config.GetEnvAsInt("ORDER_SWEEP_BATCH_SIZE", 100),
 		},
 	}
 
@@ -95,19 +156,46 @@ func main() {
 		log.Fatal("Database connection failed: ", err)
 	}
 
-	//database migrations
-	database.Migrate(db)
+	//database migrations, a half applied schema must not serve traffic
+	if err := database.Migrate(db); err != nil {
+		log.Fatal("Database migration failed: ", err)
+	}
+
+	//serve uploaded files when they are stored on disk
+	if driver := appConfig.STORAGE_CONFIG.Driver; driver == "" || driver == "local" {
+		app.Use("/media", static.New(appConfig.STORAGE_CONFIG.LocalDir))
+	}
 
 	//setup routes
-	userTransport.BootstrapUserRoutes(app, db, &appConfig.JWT_CONFIG, &appConfig.MAIL_CONFIG, &appConfig.SERVER_CONFIG)
-	productTransport.BootstrapProductRoutes(app, db, &appConfig.JWT_CONFIG)
-	orderTransport.BootstrapOrderRoutes(app, db, &appConfig.JWT_CONFIG)
+	userTransport.BootstrapUserRoutes(app, db, &appConfig.JWT_CONFIG, &appConfig.MAIL_CONFIG, &appConfig.SERVER_CONFIG, &appConfig.AUTH_CONFIG)
+	productTransport.BootstrapProductRoutes(app, db, &appConfig.JWT_CONFIG, &appConfig.STORAGE_CONFIG)
+	sweeper := orderTransport.BootstrapOrderRoutes(app, db, &appConfig.JWT_CONFIG, &appConfig.ORDER_CONFIG)
+	cartTransport.BootstrapCartRoutes(app, db, &appConfig.JWT_CONFIG, &appConfig.ORDER_CONFIG)
+	categoryTransport.BootstrapCategoryRoutes(app, db, &appConfig.JWT_CONFIG)
+	permissionTransport.BootstrapPermissionRoutes(app, db, &appConfig.JWT_CONFIG)
+	auditTransport.BootstrapAuditRoutes(app, db, &appConfig.JWT_CONFIG)
 
 	//start server
 	port := appConfig.SERVER_CONFIG.Port
 
-	log.Printf("Server starting on :%d", port)
-	if err := app.Listen(":" + strconv.Itoa(port)); err != nil {
-		log.Fatal(err)
+	//expire unpaid orders in the background
+	sweeper.Start()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Server starting on :%d", port)
+		if err := app.Listen(":" + strconv.Itoa(port)); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	//shut down on interrupt, the sweeper stops after the last request is served
+	<-ctx.Done()
+	log.Println("Shutting down")
+	if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+		log.Println("Server shutdown:", err)
 	}
+	sweeper.Stop()
 }
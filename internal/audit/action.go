@@ -0,0 +1,22 @@
+package audit
+
+type Action string
+
+// written by BaseRepository for every auditable entity
+const (
+	Created     Action = "created"
+	Updated     Action = "updated"
+	Deleted     Action = "deleted"
+	Restored    Action = "restored"
+	HardDeleted Action = "hard_deleted"
+)
+
+// recorded by services where the plain diff does not tell the whole story
+const (
+	UserRoleChanged Action = "role_changed"
+	UserSuspended   Action = "suspended"
+	UserReactivated Action = "reactivated"
+	UserPurged      Action = "purged"
+
+	RolePermissionsUpdated Action = "permissions_updated"
+)
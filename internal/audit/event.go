@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"errors"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+// AuditEvent records who did what to which entity, rows are only ever inserted
+type AuditEvent struct {
+	ActorID    *string `json:"actor_id" gorm:"type:uuid;index"`
+	Action     string  `json:"action" gorm:"not null;index"`
+	EntityType string  `json:"entity_type" gorm:"not null;index:idx_audit_entity"`
+	EntityID   string  `json:"entity_id" gorm:"not null;index:idx_audit_entity"`
+	Details    JSON    `json:"details" gorm:"type:jsonb;not null;default:'{}'"`
+	domain.BaseEntity
+}
+
+func (e *AuditEvent) GetBaseEntity() *domain.BaseEntity {
+	return &e.BaseEntity
+}
+
+// JSON is a jsonb column that is embedded as is in responses
+type JSON []byte
+
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return "{}", nil
+	}
+	return string(j), nil
+}
+
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSON(v)
+	case nil:
+		*j = nil
+	default:
+		return errors.New("unsupported type for jsonb column")
+	}
+	return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("{}"), nil
+	}
+	return j, nil
+}
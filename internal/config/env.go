@@ -15,6 +15,14 @@ type AppConfig struct {
 	MAIL_CONFIG MailConfig
 	//server
 	SERVER_CONFIG ServerConfig
+
+	//auth
+	AUTH_CONFIG AuthConfig
+
+	//uploads
+	STORAGE_CONFIG StorageConfig
+	//orders
+	ORDER_CONFIG OrderConfig
 }
 
 type DBConfig struct {
@@ -35,6 +43,8 @@ type JWTConfig struct {
 	RefreshExpiry string
 	VerifySecret  string
 	VerifyExpiry  string
+	MfaSecret     string
+	MfaExpiry     string
 }
 
 type MailConfig struct {
@@ -45,9 +55,47 @@ type MailConfig struct {
 }
 
 type ServerConfig struct {
-	Host    string
-	Port    int
-	AppName string
+	Host        string
+	Port        int
+	AppName     string
+	FrontendURL string
+}
+
+type AuthConfig struct {
+	ResetExpiry       string
+	EmailChangeExpiry string
+
+	MaxLoginAttempts   int
+	MaxIPLoginAttempts int
+	FailureWindow      string
+	LockoutBase        string
+	LockoutMax         string
+}
+
+// StorageConfig picks where uploaded files go, Driver is "local" or "s3". PublicURL is the prefix
+// files are served from, the S3 fields also work against a stand-in such as MinIO
+type StorageConfig struct {
+	Driver    string
+	PublicURL string
+
+	LocalDir string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+
+	MaxImageSize  int
+	ThumbnailSize int
+}
+
+// OrderConfig controls how long a pending order holds its stock and how often expired
+// reservations are released, the durations use time.ParseDuration syntax
+type OrderConfig struct {
+	ReservationTTL string
+	SweepInterval  string
+	SweepBatchSize int
 }
 
 func GetEnv(key string, defaultValue string) string {
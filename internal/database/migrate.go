@@ -1,21 +1,280 @@
 package database
 
 import (
+	"log"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/audit"
+	cart "github.com/QuangNV23062004/learning-go/internal/pkg/carts/domain"
+	category "github.com/QuangNV23062004/learning-go/internal/pkg/categories/domain"
 	order "github.com/QuangNV23062004/learning-go/internal/pkg/orders/domain"
+	orderEnums "github.com/QuangNV23062004/learning-go/internal/pkg/orders/enums"
+	permission "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/domain"
+	permissionEnums "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
 	product "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
 	user "github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func Migrate(db *gorm.DB) error {
 	err := db.AutoMigrate(
 		&user.User{},
+		&user.Session{},
+		&user.PendingUser{},
+		&user.UserToken{},
+		&user.LoginThrottle{},
+		&user.RecoveryCode{},
 		&product.Product{},
+		&product.Tag{},
+		&product.ProductTag{},
+		&product.ProductCategory{},
+		&product.ProductImage{},
+		&product.ProductOption{},
+		&product.ProductVariant{},
+		&product.InventoryMovement{},
+		&category.Category{},
 		&order.Order{},
+		&order.OrderItem{},
+		&cart.CartItem{},
+		&permission.Permission{},
+		&permission.RolePermission{},
+		&audit.AuditEvent{},
+		&appliedMigration{},
 	)
 	if err != nil {
 		return err
 	}
+
+	if err := migrateSingleProductOrders(db); err != nil {
+		return err
+	}
+
+	if err := migrateDeletedOrderStatus(db); err != nil {
+		return err
+	}
+
+	if err := migrateOrderItemSnapshots(db); err != nil {
+		return err
+	}
+
+	if err := migrateProductSearch(db); err != nil {
+		return err
+	}
+
+	if err := migrateProductVariants(db); err != nil {
+		return err
+	}
+
+	if err := runOnce(db, "inventory_opening_balance", postOpeningBalances); err != nil {
+		return err
+	}
+
+	if err := checkInventoryDrift(db); err != nil {
+		return err
+	}
+
+	if err := seedPermissions(db); err != nil {
+		return err
+	}
+	return nil
+}
+
+// orders used to carry one product_id and quantity, move them to order_items
+func migrateSingleProductOrders(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&order.Order{}, "product_id") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO order_items (order_id, product_id, quantity, unit_price, subtotal, created_at, updated_at)
+			SELECT orders.id, orders.product_id, orders.quantity,
+				CASE WHEN orders.quantity > 0 THEN orders.total / orders.quantity ELSE 0 END,
+				orders.total, orders.created_at, orders.updated_at
+			FROM orders
+			WHERE NOT EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id)`).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().DropColumn(&order.Order{}, "product_id"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&order.Order{}, "quantity")
+	})
+}
+
+// soft deleted orders used to stand for cancelled ones and their stock was already given back
+func migrateDeletedOrderStatus(db *gorm.DB) error {
+	return db.Model(&order.Order{}).
+		Where("is_deleted = ? AND status = ?", true, orderEnums.Pending).
+		Update("status", orderEnums.Cancelled).Error
+}
+
+// lines created before snapshots existed copy the current product name and currency once
+func migrateOrderItemSnapshots(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE order_items SET product_name = products.name, currency = products.currency
+			FROM products
+			WHERE products.id = order_items.product_id AND order_items.product_name = ''`).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			UPDATE orders SET currency = order_items.currency
+			FROM order_items
+			WHERE order_items.order_id = orders.id AND orders.currency <> order_items.currency`).Error
+	})
+}
+
+// search_vector is a generated column so postgres keeps it in sync with every write to the name,
+// pg_trgm backs the typo tolerant fallback of the product search
+func migrateProductSearch(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+			`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (to_tsvector('simple', coalesce(name, ''))) STORED`,
+			`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+			`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// products from before variants get a single variant holding their stock, its sku is the product id.
+// Their order lines are pointed at that variant so cancelling them restocks it
+func migrateProductVariants(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO product_variants (product_id, sku, options, stock, created_at, updated_at)
+			SELECT products.id, products.id::text, '{}', products.stock, products.created_at, products.updated_at
+			FROM products
+			WHERE NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id)`).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`
+			UPDATE order_items SET variant_id = product_variants.id, sku = product_variants.sku
+			FROM product_variants
+			WHERE product_variants.product_id = order_items.product_id
+				AND product_variants.options = '{}'::jsonb
+				AND order_items.variant_id IS NULL`).Error
+	})
+}
+
+// appliedMigration records a one-off data migration so later boots skip it
+type appliedMigration struct {
+	Name      string    `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// runOnce applies the data migration and records it in the same transaction. A second server booting
+// at the same time waits on the record and then skips the migration
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&appliedMigration{Name: name, AppliedAt: time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return migrate(tx)
+	})
+}
+
+// postOpeningBalances makes the ledger the source of truth: stock from before the ledger is posted
+// as an opening balance, so every variant's movements add up to its stock
+func postOpeningBalances(tx *gorm.DB) error {
+	return tx.Exec(`
+		INSERT INTO inventory_movements (product_id, variant_id, quantity, stock_after, reason, note, created_at, updated_at)
+		SELECT product_variants.product_id, product_variants.id, product_variants.stock, product_variants.stock,
+			'adjustment', 'opening balance', NOW(), NOW()
+		FROM product_variants
+		WHERE product_variants.stock <> 0
+			AND NOT EXISTS (SELECT 1 FROM inventory_movements WHERE inventory_movements.variant_id = product_variants.id)`).Error
+}
+
+// checkInventoryDrift logs every variant whose stock is not what its ledger adds up to and every product
+// whose stock is not the sum of its live variants. The counters are left alone so the drift can be looked into
+func checkInventoryDrift(db *gorm.DB) error {
+	var variants []struct {
+		ID     string
+		Stock  int
+		Ledger int
+	}
+	err := db.Raw(`
+		SELECT product_variants.id, product_variants.stock, COALESCE(ledger.stock, 0) AS ledger
+		FROM product_variants
+		LEFT JOIN (SELECT variant_id, SUM(quantity) AS stock FROM inventory_movements GROUP BY variant_id) AS ledger
+			ON ledger.variant_id = product_variants.id
+		WHERE product_variants.stock <> COALESCE(ledger.stock, 0)`).Scan(&variants).Error
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		log.Printf("Inventory drift: variant %s has stock %d but its ledger adds up to %d", variant.ID, variant.Stock, variant.Ledger)
+	}
+
+	var products []struct {
+		ID       string
+		Stock    int
+		Variants int
+	}
+	err = db.Raw(`
+		SELECT products.id, products.stock, COALESCE(SUM(product_variants.stock), 0) AS variants
+		FROM products
+		LEFT JOIN product_variants ON product_variants.product_id = products.id AND product_variants.is_deleted = false
+		GROUP BY products.id, products.stock
+		HAVING products.stock <> COALESCE(SUM(product_variants.stock), 0)`).Scan(&products).Error
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		log.Printf("Inventory drift: product %s has stock %d but its live variants hold %d", product.ID, product.Stock, product.Variants)
+	}
 	return nil
 }
+
+// permissions seen for the first time are granted to their default roles,
+// already known ones are left alone so admin edits survive restarts
+func seedPermissions(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, known := range permissionEnums.All {
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&permission.Permission{
+				Name:        string(known.Name),
+				Description: known.Description,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			for _, role := range permissionEnums.DefaultRoles(known.Name) {
+				err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&permission.RolePermission{
+					Role:       role,
+					Permission: string(known.Name),
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -8,4 +8,10 @@ type BaseEntity struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	IsDeleted bool      `json:"is_deleted" gorm:"default:false"`
 	DeletedAt string    `json:"deleted_at" gorm:"default:null"`
+	Version   int       `json:"version" gorm:"not null;default:1"`
+}
+
+// MatchesVersion reports whether the caller saw the current version, 0 means no version was sent
+func (e *BaseEntity) MatchesVersion(version int) bool {
+	return version == 0 || e.Version == version
 }
@@ -0,0 +1,10 @@
+package domain
+
+import "errors"
+
+var (
+	ErrVersionConflict = errors.New("resource was modified by another request")
+	ErrInvalidCursor   = errors.New("invalid pagination cursor")
+	ErrInvalidFilter   = errors.New("invalid filter")
+	ErrInvalidQuery    = errors.New("invalid query parameters")
+)
@@ -0,0 +1,19 @@
+package http
+
+import "github.com/gofiber/fiber/v3"
+
+// Subject is the id of the signed in caller, ErrUnauthorized when the request got through without one,
+// e.g. on a route registered before the auth middleware by mistake
+func Subject(c fiber.Ctx) (string, error) {
+	sub, _ := c.Locals("sub").(string)
+	if sub == "" {
+		return "", ErrUnauthorized
+	}
+	return sub, nil
+}
+
+// Role is the role of the caller, empty for anonymous callers of public routes
+func Role(c fiber.Ctx) string {
+	role, _ := c.Locals("role").(string)
+	return role
+}
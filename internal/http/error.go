@@ -3,7 +3,11 @@ package http
 import (
 	"errors"
 
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
+	cartDomain "github.com/QuangNV23062004/learning-go/internal/pkg/carts/domain"
+	categoryDomain "github.com/QuangNV23062004/learning-go/internal/pkg/categories/domain"
 	orderDomain "github.com/QuangNV23062004/learning-go/internal/pkg/orders/domain"
+	permissionDomain "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/domain"
 	productDomain "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
 	userDomain "github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
 )
@@ -13,16 +17,74 @@ var (
 	ErrInvalidBody         = errors.New("invalid request body")
 	ErrUnauthorized        = errors.New("unauthorized")
 	ErrForbidden           = errors.New("forbidden")
-	ErrInvalidQuery        = errors.New("invalid query parameters")
+	ErrInvalidQuery        = baseDomain.ErrInvalidQuery
 	ErrMissingRefreshToken = errors.New("missing refresh token in cookies")
+	ErrInvalidIfMatch      = errors.New("invalid If-Match header")
 )
 
+// DetailedError carries data the client needs beyond the message, e.g. the cart lines that stop a checkout
+type DetailedError interface {
+	error
+	Details() interface{}
+}
+
+// GetDetails returns the details of the first DetailedError in the chain, nil when there is none
+func GetDetails(err error) interface{} {
+	var detailed DetailedError
+	if errors.As(err, &detailed) {
+		return detailed.Details()
+	}
+	return nil
+}
+
 func GetStatusCode(err error) int {
 	switch {
 	case errors.Is(err, userDomain.ErrUserAlreadyExists):
 		return 400
 	case errors.Is(err, userDomain.ErrInvalidCredentials):
 		return 400
+	case errors.Is(err, userDomain.ErrInvalidRefreshToken):
+		return 401
+	case errors.Is(err, userDomain.ErrRefreshTokenReused):
+		return 401
+	case errors.Is(err, userDomain.ErrInvalidResetToken):
+		return 400
+	case errors.Is(err, userDomain.ErrIncorrectPassword):
+		return 400
+	case errors.Is(err, userDomain.ErrEmailTaken):
+		return 409
+	case errors.Is(err, userDomain.ErrSameEmail):
+		return 400
+	case errors.Is(err, userDomain.ErrInvalidEmailChangeToken):
+		return 400
+	case errors.Is(err, userDomain.ErrAccountLocked):
+		return 423
+	case errors.Is(err, userDomain.ErrTooManyAttempts):
+		return 429
+	case errors.Is(err, userDomain.ErrTwoFactorAlreadyEnabled):
+		return 409
+	case errors.Is(err, userDomain.ErrTwoFactorNotEnabled):
+		return 400
+	case errors.Is(err, userDomain.ErrTwoFactorNotSetUp):
+		return 400
+	case errors.Is(err, userDomain.ErrInvalidTwoFactorCode):
+		return 401
+	case errors.Is(err, userDomain.ErrInvalidMfaToken):
+		return 401
+	case errors.Is(err, userDomain.ErrAccountSuspended):
+		return 403
+	case errors.Is(err, userDomain.ErrLastAdmin):
+		return 409
+	case errors.Is(err, userDomain.ErrInvalidRole):
+		return 400
+	case errors.Is(err, userDomain.ErrProductsOnOpenOrders):
+		return 409
+	case errors.Is(err, permissionDomain.ErrUnknownRole):
+		return 404
+	case errors.Is(err, permissionDomain.ErrUnknownPermission):
+		return 400
+	case errors.Is(err, permissionDomain.ErrPermissionLockout):
+		return 409
 	case errors.Is(err, ErrUnauthorized):
 		return 401
 	case errors.Is(err, ErrForbidden):
@@ -31,6 +93,60 @@ func GetStatusCode(err error) int {
 		return 400
 	case errors.Is(err, ErrMissingRefreshToken):
 		return 400
+	case errors.Is(err, ErrInvalidIfMatch):
+		return 400
+	case errors.Is(err, baseDomain.ErrVersionConflict):
+		return 409
+	case errors.Is(err, baseDomain.ErrInvalidCursor):
+		return 400
+	case errors.Is(err, baseDomain.ErrInvalidFilter):
+		return 400
+	case errors.Is(err, ErrTooManyFilters):
+		return 400
+	case errors.Is(err, categoryDomain.ErrCategoryNotFound):
+		return 404
+	case errors.Is(err, categoryDomain.ErrParentCategoryNotFound):
+		return 400
+	case errors.Is(err, categoryDomain.ErrCategoryCycle):
+		return 400
+	case errors.Is(err, categoryDomain.ErrCategoryHasChildren):
+		return 409
+	case errors.Is(err, categoryDomain.ErrSlugTaken):
+		return 409
+	case errors.Is(err, categoryDomain.ErrInvalidSlug):
+		return 400
+	case errors.Is(err, productDomain.ErrUnknownCategory):
+		return 400
+	case errors.Is(err, productDomain.ErrVariantNotFound):
+		return 404
+	case errors.Is(err, productDomain.ErrInvalidVariant):
+		return 400
+	case errors.Is(err, productDomain.ErrInvalidOption):
+		return 400
+	case errors.Is(err, productDomain.ErrDuplicateVariant):
+		return 400
+	case errors.Is(err, productDomain.ErrSKUTaken):
+		return 409
+	case errors.Is(err, productDomain.ErrInvalidSKU):
+		return 400
+	case errors.Is(err, productDomain.ErrInvalidStock):
+		return 400
+	case errors.Is(err, productDomain.ErrInvalidPrice):
+		return 400
+	case errors.Is(err, productDomain.ErrNoVariants):
+		return 400
+	case errors.Is(err, productDomain.ErrProductHasVariants):
+		return 409
+	case errors.Is(err, productDomain.ErrInvalidMovement):
+		return 400
+	case errors.Is(err, productDomain.ErrImageNotFound):
+		return 404
+	case errors.Is(err, productDomain.ErrImageTooLarge):
+		return 413
+	case errors.Is(err, productDomain.ErrUnsupportedImage):
+		return 415
+	case errors.Is(err, productDomain.ErrInvalidImageOrder):
+		return 400
 	case errors.Is(err, productDomain.ErrProductNotFound):
 		return 404
 	case errors.Is(err, productDomain.ErrUserNotFound):
@@ -44,9 +160,41 @@ func GetStatusCode(err error) int {
 	case errors.Is(err, orderDomain.ErrInsufficientStock):
 		return 400
 	case errors.Is(err, orderDomain.ErrNotAllowed):
-		return 40
+		return 403
 	case errors.Is(err, orderDomain.ErrOldProductNotFound):
 		return 400
+	case errors.Is(err, orderDomain.ErrEmptyOrder):
+		return 400
+	case errors.Is(err, orderDomain.ErrInvalidTransition):
+		return 409
+	case errors.Is(err, orderDomain.ErrOrderNotEditable):
+		return 409
+	case errors.Is(err, orderDomain.ErrCurrencyMismatch):
+		return 400
+	case errors.Is(err, orderDomain.ErrVariantNotFound):
+		return 404
+	case errors.Is(err, orderDomain.ErrVariantRequired):
+		return 400
+	case errors.Is(err, orderDomain.ErrReservationExpired):
+		return 409
+	case errors.Is(err, cartDomain.ErrCartItemNotFound):
+		return 404
+	case errors.Is(err, cartDomain.ErrProductNotFound):
+		return 404
+	case errors.Is(err, cartDomain.ErrVariantNotFound):
+		return 404
+	case errors.Is(err, cartDomain.ErrVariantRequired):
+		return 400
+	case errors.Is(err, cartDomain.ErrInvalidQuantity):
+		return 400
+	case errors.Is(err, cartDomain.ErrNotEnoughStock):
+		return 409
+	case errors.Is(err, cartDomain.ErrCurrencyMismatch):
+		return 400
+	case errors.Is(err, cartDomain.ErrEmptyCart):
+		return 400
+	case errors.Is(err, cartDomain.ErrCheckoutBlocked):
+		return 409
 
 	default:
 		return 500
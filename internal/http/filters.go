@@ -0,0 +1,43 @@
+package http
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+const maxFilters = 20
+
+var ErrTooManyFilters = errors.New("too many filters")
+
+// field[op], the field may be qualified with a related table like products.name
+var filterKey = regexp.MustCompile(`^([a-z_]+(?:\.[a-z_]+)?)\[([a-z]+)\]$`)
+
+// ParseFilters collects the field[op]=value query parameters, other parameters are left to the query dto
+func ParseFilters(c fiber.Ctx) ([]types.Filter, error) {
+	var filters []types.Filter
+	for key, value := range c.Queries() {
+		match := filterKey.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		filters = append(filters, types.Filter{Field: match[1], Operator: match[2], Value: value})
+	}
+
+	if len(filters) > maxFilters {
+		return nil, ErrTooManyFilters
+	}
+
+	// keep the generated sql stable between requests
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Field != filters[j].Field {
+			return filters[i].Field < filters[j].Field
+		}
+		return filters[i].Operator < filters[j].Operator
+	})
+	return filters, nil
+}
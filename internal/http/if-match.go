@@ -0,0 +1,26 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ParseIfMatch reads the expected entity version from the If-Match header, 0 when it is absent
+func ParseIfMatch(c fiber.Ctx) (int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	header = strings.TrimPrefix(header, "W/")
+	header = strings.Trim(header, `"`)
+
+	version, err := strconv.Atoi(header)
+	if err != nil || version < 1 {
+		return 0, ErrInvalidIfMatch
+	}
+
+	return version, nil
+}
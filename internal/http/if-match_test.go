@@ -0,0 +1,55 @@
+package http
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    int
+		wantErr error
+	}{
+		{name: "absent", header: "", want: 0},
+		{name: "any version", header: "*", want: 0},
+		{name: "strong etag", header: `"3"`, want: 3},
+		{name: "weak etag", header: `W/"12"`, want: 12},
+		{name: "bare number", header: " 7 ", want: 7},
+		{name: "zero", header: `"0"`, wantErr: ErrInvalidIfMatch},
+		{name: "negative", header: `"-1"`, wantErr: ErrInvalidIfMatch},
+		{name: "not a number", header: `"abc"`, wantErr: ErrInvalidIfMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var version int
+			var err error
+
+			app := fiber.New()
+			app.Get("/", func(c fiber.Ctx) error {
+				version, err = ParseIfMatch(c)
+				return nil
+			})
+
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderIfMatch, tt.header)
+			}
+			if _, testErr := app.Test(req); testErr != nil {
+				t.Fatal(testErr)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error is %v, want %v", err, tt.wantErr)
+			}
+			if version != tt.want {
+				t.Errorf("version is %d, want %d", version, tt.want)
+			}
+		})
+	}
+}
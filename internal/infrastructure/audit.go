@@ -0,0 +1,131 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/QuangNV23062004/learning-go/internal/audit"
+
+	"gorm.io/gorm"
+)
+
+// Auditable entities get an audit event, in the same transaction, for every write through BaseRepository
+type Auditable interface {
+	AuditType() string
+}
+
+type actorKey struct{}
+
+// fields that change on every write or must never be copied into the log
+var (
+	ignoredAuditFields  = map[string]bool{"version": true, "updated_at": true}
+	redactedAuditFields = map[string]bool{"password": true}
+)
+
+// WithActor tags the connection with the user doing the change, transactions opened from it inherit the actor
+func WithActor(db *gorm.DB, actorID string) *gorm.DB {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return db.WithContext(context.WithValue(ctx, actorKey{}, actorID))
+}
+
+// ActorFrom is the user set with WithActor, nil for changes made by the system
+func ActorFrom(db *gorm.DB) *string {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	actorID, _ := db.Statement.Context.Value(actorKey{}).(string)
+	if actorID == "" {
+		return nil
+	}
+	return &actorID
+}
+
+// audit records the change from before to after, a nil side means the row did not exist
+func (r *BaseRepository[T]) audit(action audit.Action, entityID string, before interface{}, after interface{}, tx *gorm.DB) error {
+	var auditable Auditable
+	var ok bool
+	if after != nil {
+		auditable, ok = after.(Auditable)
+	} else {
+		auditable, ok = before.(Auditable)
+	}
+	if !ok {
+		return nil
+	}
+
+	from, err := auditSnapshot(before)
+	if err != nil {
+		return err
+	}
+	to, err := auditSnapshot(after)
+	if err != nil {
+		return err
+	}
+
+	details, err := json.Marshal(map[string]interface{}{"changes": auditDiff(from, to)})
+	if err != nil {
+		return err
+	}
+
+	db := r.GetDatabase(tx)
+	return db.Create(&audit.AuditEvent{
+		ActorID:    ActorFrom(db),
+		Action:     string(action),
+		EntityType: auditable.AuditType(),
+		EntityID:   entityID,
+		Details:    details,
+	}).Error
+}
+
+// auditSnapshot reads the entity through its json tags so hidden fields stay hidden
+func auditSnapshot(entity interface{}) (map[string]interface{}, error) {
+	snapshot := map[string]interface{}{}
+	if entity == nil {
+		return snapshot, nil
+	}
+
+	raw, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func auditDiff(from map[string]interface{}, to map[string]interface{}) map[string]interface{} {
+	changes := map[string]interface{}{}
+
+	keys := map[string]bool{}
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		if ignoredAuditFields[key] {
+			continue
+		}
+
+		before, after := from[key], to[key]
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		if redactedAuditFields[key] {
+			changes[key] = map[string]interface{}{"changed": true}
+			continue
+		}
+
+		changes[key] = map[string]interface{}{"from": before, "to": after}
+	}
+
+	return changes
+}
@@ -2,13 +2,14 @@ package infrastructure
 
 import (
 	"fmt"
-	"math"
 	"time"
 
+	"github.com/QuangNV23062004/learning-go/internal/audit"
 	"github.com/QuangNV23062004/learning-go/internal/domain"
 	"github.com/QuangNV23062004/learning-go/internal/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BaseModel interface {
@@ -16,13 +17,24 @@ type BaseModel interface {
 }
 
 type BaseRepository[T BaseModel] struct {
-	db *gorm.DB
+	db   *gorm.DB
+	spec ListSpec
 }
 
 func NewBaseRepository[T BaseModel](db *gorm.DB) *BaseRepository[T] {
 	return &BaseRepository[T]{db: db}
 }
 
+// WithListSpec sets what Paginated may sort, search and filter by, without it every list query is rejected
+func (r *BaseRepository[T]) WithListSpec(spec ListSpec) *BaseRepository[T] {
+	r.spec = spec
+	return r
+}
+
+func (r *BaseRepository[T]) ListSpec() ListSpec {
+	return r.spec
+}
+
 func (r *BaseRepository[T]) GetDatabase(tx *gorm.DB) *gorm.DB {
 	if tx == nil {
 		return r.db
@@ -52,21 +64,21 @@ func (r *BaseRepository[T]) Create(entity T, tx *gorm.DB) (T, error) {
 		var zero T
 		return zero, err
 	}
+
+	if err := r.audit(audit.Created, entity.GetBaseEntity().ID, nil, entity, tx); err != nil {
+		var zero T
+		return zero, err
+	}
 	return entity, nil
 }
 
 func (r *BaseRepository[T]) Update(entity T, tx *gorm.DB) (T, error) {
-	db := r.GetDatabase(tx)
 	be := entity.GetBaseEntity()
 	if be == nil {
 		var zero T
 		return zero, fmt.Errorf("missing base entity")
 	}
-	if _, err := r.FindByID(be.ID, false, tx); err != nil {
-		var zero T
-		return zero, err
-	}
-	if err := db.Model(&entity).Where("id = ?", be.ID).Save(&entity).Error; err != nil {
+	if err := r.compareAndSave(entity, false, audit.Updated, tx); err != nil {
 		var zero T
 		return zero, err
 	}
@@ -82,8 +94,7 @@ func (r *BaseRepository[T]) Delete(id string, tx *gorm.DB) (bool, error) {
 		be.IsDeleted = true
 		be.DeletedAt = time.Now().Format(time.RFC3339)
 	}
-	db := r.GetDatabase(tx)
-	if err := db.Model(&entity).Where("id = ?", id).Save(&entity).Error; err != nil {
+	if err := r.compareAndSave(entity, false, audit.Deleted, tx); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -94,17 +105,57 @@ func (r *BaseRepository[T]) Restore(id string, tx *gorm.DB) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	db := r.GetDatabase(tx)
 	if be := entity.GetBaseEntity(); be != nil {
 		be.IsDeleted = false
 		be.DeletedAt = ""
 	}
-	if err := db.Model(&entity).Where("id = ?", id).Save(&entity).Error; err != nil {
+	if err := r.compareAndSave(entity, true, audit.Restored, tx); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+// compareAndSave writes every column only if the row still has the version the entity was read at,
+// the version is bumped on success and ErrVersionConflict is returned when another write got there first
+func (r *BaseRepository[T]) compareAndSave(entity T, includeDeleted bool, action audit.Action, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	be := entity.GetBaseEntity()
+
+	// the stored row is the "before" side of the audit diff
+	var before interface{}
+	if _, ok := any(entity).(Auditable); ok {
+		stored, err := r.FindByID(be.ID, true, tx)
+		if err != nil {
+			return err
+		}
+		before = stored
+	}
+
+	current := be.Version
+	be.Version = current + 1
+
+	where := db.Model(entity).Omit(clause.Associations).Where("id = ? AND version = ?", be.ID, current)
+	if !includeDeleted {
+		where = where.Where("is_deleted = ?", false)
+	}
+
+	result := where.Select("*").Updates(entity)
+	if result.Error != nil {
+		be.Version = current
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		be.Version = current
+		if _, err := r.FindByID(be.ID, includeDeleted, tx); err != nil {
+			return err
+		}
+		return domain.ErrVersionConflict
+	}
+
+	return r.audit(action, be.ID, before, entity, tx)
+}
+
 func (r *BaseRepository[T]) FindAll(includeDeleted bool, tx *gorm.DB) ([]T, error) {
 	var entities []T
 	where := r.GetDatabase(tx).Model(new(T))
@@ -126,42 +177,31 @@ func (r *BaseRepository[T]) HardDelete(id string, tx *gorm.DB) (bool, error) {
 	if err := db.Unscoped().Delete(&entity).Error; err != nil {
 		return false, err
 	}
+
+	if err := r.audit(audit.HardDeleted, id, entity, nil, tx); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
-// parameters should be validated in service layer
-func (r *BaseRepository[T]) Paginated(page int, limit int, search, searchField, order, sortBy string, includeDeleted bool, tx *gorm.DB) (*types.Paginated[T], error) {
-	var entities []T
-	where := r.GetDatabase(tx).Model(new(T))
-
-	if search != "" && searchField != "" {
-		where = where.Where(fmt.Sprintf("%s ILIKE ?", searchField), "%"+search+"%")
+// sort, search and filters are checked against the list spec, a non empty cursor switches to keyset pagination
+func (r *BaseRepository[T]) Paginated(page int, limit int, cursor, search, searchField, order, sortBy string, filters []types.Filter, includeDeleted bool, tx *gorm.DB) (*types.Paginated[T], error) {
+	sort, err := r.spec.ParseSort(sortBy, order)
+	if err != nil {
+		return nil, err
 	}
+
+	where := r.GetDatabase(tx).Model(new(T))
 	if !includeDeleted {
 		where = where.Where("is_deleted = ?", false)
 	}
 
-	take := limit
-	skip := (page - 1) * limit
-
-	if err := where.Order(sortBy + " " + order).Offset(skip).Limit(take).Find(&entities).Error; err != nil {
+	if where, err = r.spec.ApplySearch(where, search, searchField); err != nil {
 		return nil, err
 	}
-
-	var total int64
-	if err := where.Count(&total).Error; err != nil {
+	if where, err = ApplyFilters(where, filters, r.spec.Filters); err != nil {
 		return nil, err
 	}
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
-
-	return &types.Paginated[T]{
-		Data:        entities,
-		TotalPages:  totalPages,
-		CurrentPage: page,
-		Limit:       limit,
-		Order:       order,
-		SortBy:      sortBy,
-		HasPrevious: page > 1,
-		HasNext:     page < totalPages,
-	}, nil
+
+	return Paginate[T](where, nil, page, limit, cursor, sort)
 }
@@ -0,0 +1,210 @@
+package infrastructure
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// pageCursor is the decoded form of next_cursor, it remembers the sort it was issued for so it
+// can't be replayed against another ordering
+type pageCursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+	ID     string `json:"id"`
+}
+
+var cursorSchemas sync.Map
+
+// MaxPageLimit is the most rows one page may hold
+const MaxPageLimit = 100
+
+// ClampPage keeps page at 1 or more and limit between 1 and MaxPageLimit, query tags are not
+// validated so out of range values can reach the repositories
+func ClampPage(page int, limit int) (int, int) {
+	return max(page, 1), min(max(limit, 1), MaxPageLimit)
+}
+
+// Paginate loads one page of where ordered by sort and then id. With a cursor the page starts
+// after the row the cursor was issued for and the count is skipped, otherwise it is read by offset.
+// count may be nil to count the rows of where
+func Paginate[E any](where *gorm.DB, count *gorm.DB, page int, limit int, cursor string, sort Sort) (*types.Paginated[E], error) {
+	page, limit = ClampPage(page, limit)
+
+	rowSchema, err := schema.Parse(new(E), &cursorSchemas, where.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// the id breaks ties in the direction of the last key
+	idDesc := len(sort) > 0 && sort[len(sort)-1].Desc
+	keys := append(append(Sort{}, sort...), SortKey{Name: "id", Column: "id", Desc: idDesc})
+	fields := make([]*schema.Field, len(keys))
+	for i, key := range keys {
+		if fields[i] = rowSchema.LookUpField(key.Column); fields[i] == nil {
+			return nil, domain.ErrInvalidQuery
+		}
+	}
+
+	result := &types.Paginated[E]{
+		Limit:  limit,
+		Order:  sort.Order(),
+		SortBy: sort.String(),
+	}
+
+	query := where.Session(&gorm.Session{})
+	if cursor != "" {
+		after, err := decodeCursor(cursor, sort, fields)
+		if err != nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		query = query.Where(keysetCondition(keys, append(after.Values, after.ID)))
+		result.HasPrevious = true
+	} else {
+		if count == nil {
+			count = where
+		}
+		var total int64
+		if err := count.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		result.TotalPages = int(math.Ceil(float64(total) / float64(limit)))
+		result.CurrentPage = page
+		result.HasPrevious = page > 1
+		query = query.Offset((page - 1) * limit)
+	}
+
+	for _, key := range keys {
+		query = query.Order(clause.OrderByColumn{Column: sortColumn(key), Desc: key.Desc})
+	}
+
+	// one extra row tells whether another page follows
+	var rows []E
+	if err := query.Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := reflect.Indirect(reflect.ValueOf(rows[len(rows)-1]))
+		next := pageCursor{Sort: sort.String()}
+		for i, field := range fields {
+			value, _ := field.ValueOf(where.Statement.Context, last)
+			if i == len(fields)-1 {
+				next.ID, _ = value.(string)
+			} else {
+				next.Values = append(next.Values, value)
+			}
+		}
+		encoded, err := encodeCursor(next)
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = encoded
+		result.HasNext = true
+	}
+	result.Data = rows
+
+	return result, nil
+}
+
+func sortColumn(key SortKey) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: key.Column}
+}
+
+// keysetCondition matches the rows after values in the order of keys. When every key runs the
+// same way a row comparison is enough, mixed directions expand to
+// (a > x) OR (a = x AND b < y) OR ...
+func keysetCondition(keys Sort, values []any) clause.Expression {
+	sameDirection := true
+	for _, key := range keys {
+		sameDirection = sameDirection && key.Desc == keys[0].Desc
+	}
+
+	if sameDirection {
+		operator := ">"
+		if keys[0].Desc {
+			operator = "<"
+		}
+		columns := make([]string, len(keys))
+		vars := make([]any, 0, len(keys)*2)
+		for i, key := range keys {
+			columns[i] = "?"
+			vars = append(vars, sortColumn(key))
+		}
+		vars = append(vars, values...)
+		placeholders := strings.Join(columns, ", ")
+		return clause.Expr{SQL: "(" + placeholders + ") " + operator + " (" + placeholders + ")", Vars: vars}
+	}
+
+	var alternatives []string
+	var vars []any
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, "? = ?")
+			vars = append(vars, sortColumn(keys[j]), values[j])
+		}
+		operator := ">"
+		if key.Desc {
+			operator = "<"
+		}
+		parts = append(parts, "? "+operator+" ?")
+		vars = append(vars, sortColumn(key), values[i])
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+	return clause.Expr{SQL: "(" + strings.Join(alternatives, " OR ") + ")", Vars: vars}
+}
+
+func encodeCursor(cursor pageCursor) (string, error) {
+	raw, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor turns the cursor back into query values, timestamps come back from json as strings
+func decodeCursor(encoded string, sort Sort, fields []*schema.Field) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort.String() || cursor.ID == "" || len(cursor.Values) != len(sort) {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	for i, value := range cursor.Values {
+		if value == nil {
+			return nil, domain.ErrInvalidCursor
+		}
+		if fields[i].IndirectFieldType != reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, domain.ErrInvalidCursor
+		}
+		at, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return nil, err
+		}
+		cursor.Values[i] = at
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,135 @@
+package infrastructure
+
+import (
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type cursorRow struct {
+	ID        string
+	Price     float64
+	CreatedAt time.Time
+}
+
+var cursorSort = Sort{
+	{Name: "price", Column: "price"},
+	{Name: "created_at", Column: "created_at", Desc: true},
+}
+
+func cursorFields(t *testing.T) []*schema.Field {
+	t.Helper()
+
+	rowSchema, err := schema.Parse(&cursorRow{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make([]*schema.Field, len(cursorSort))
+	for i, key := range cursorSort {
+		fields[i] = rowSchema.LookUpField(key.Column)
+	}
+	return fields
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	encoded, err := encodeCursor(pageCursor{Sort: cursorSort.String(), Values: []any{9.5, at}, ID: "row-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := decodeCursor(encoded, cursorSort, cursorFields(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cursor.ID != "row-1" {
+		t.Errorf("id is %q, want row-1", cursor.ID)
+	}
+	if cursor.Values[0] != 9.5 {
+		t.Errorf("price is %v, want 9.5", cursor.Values[0])
+	}
+	if got, ok := cursor.Values[1].(time.Time); !ok || !got.Equal(at) {
+		t.Errorf("created_at is %#v, want %v", cursor.Values[1], at)
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	raw := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "not base64", encoded: "not a cursor!"},
+		{name: "not json", encoded: raw("{")},
+		{name: "other sort", encoded: raw(`{"s":"price","v":[1],"id":"row-1"}`)},
+		{name: "missing id", encoded: raw(`{"s":"price,-created_at","v":[1,"2024-03-01T00:00:00Z"]}`)},
+		{name: "missing value", encoded: raw(`{"s":"price,-created_at","v":[1],"id":"row-1"}`)},
+		{name: "null value", encoded: raw(`{"s":"price,-created_at","v":[null,"2024-03-01T00:00:00Z"],"id":"row-1"}`)},
+		{name: "time as a number", encoded: raw(`{"s":"price,-created_at","v":[1,5],"id":"row-1"}`)},
+		{name: "invalid time", encoded: raw(`{"s":"price,-created_at","v":[1,"yesterday"],"id":"row-1"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.encoded, cursorSort, cursorFields(t)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestClampPage(t *testing.T) {
+	tests := []struct {
+		page, limit         int
+		wantPage, wantLimit int
+	}{
+		{page: 3, limit: 20, wantPage: 3, wantLimit: 20},
+		{page: 0, limit: 0, wantPage: 1, wantLimit: 1},
+		{page: -2, limit: -1, wantPage: 1, wantLimit: 1},
+		{page: 1, limit: 1000, wantPage: 1, wantLimit: MaxPageLimit},
+	}
+
+	for _, tt := range tests {
+		page, limit := ClampPage(tt.page, tt.limit)
+		if page != tt.wantPage || limit != tt.wantLimit {
+			t.Errorf("ClampPage(%d, %d) = %d, %d, want %d, %d", tt.page, tt.limit, page, limit, tt.wantPage, tt.wantLimit)
+		}
+	}
+}
+
+// out of range pages and limits must not reach the slicing of the result
+func TestPaginateClampsLimit(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{-1, 0, 1000} {
+		result, err := Paginate[cursorRow](db.Model(&cursorRow{}), nil, -1, limit, "", cursorSort)
+		if err != nil {
+			t.Fatalf("limit %d: unexpected error: %v", limit, err)
+		}
+		if result.Limit < 1 || result.Limit > MaxPageLimit || result.CurrentPage != 1 {
+			t.Errorf("limit %d: page %d with limit %d", limit, result.CurrentPage, result.Limit)
+		}
+	}
+
+	if _, err := Paginate[cursorRow](db.Model(&cursorRow{}), nil, 1, 10, "tampered", cursorSort); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("error is %v, want ErrInvalidCursor", err)
+	}
+}
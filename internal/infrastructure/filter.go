@@ -0,0 +1,178 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FilterKind int
+
+const (
+	FilterString FilterKind = iota
+	FilterNumber
+	FilterTime
+	FilterBool
+	FilterUUID
+)
+
+// FilterField maps a public filter name to a column. Column is either a column of the listed
+// table or table.column, Related wraps the condition when the column lives on another table,
+// e.g. "orders.id IN (SELECT order_id FROM order_items WHERE ?)"
+type FilterField struct {
+	Column  string
+	Kind    FilterKind
+	Related string
+}
+
+// FilterFields is the whitelist of fields a repository accepts filters on
+type FilterFields map[string]FilterField
+
+const (
+	opEq      = "eq"
+	opNe      = "ne"
+	opGt      = "gt"
+	opGte     = "gte"
+	opLt      = "lt"
+	opLte     = "lte"
+	opLike    = "like"
+	opIn      = "in"
+	opBetween = "between"
+	opNull    = "null"
+)
+
+var comparisons = map[string]string{
+	opEq:  "=",
+	opNe:  "<>",
+	opGt:  ">",
+	opGte: ">=",
+	opLt:  "<",
+	opLte: "<=",
+}
+
+// ApplyFilters adds every filter to where as a parameterized condition, a field outside the
+// whitelist or a value that doesn't fit the column is rejected with ErrInvalidFilter
+func ApplyFilters(where *gorm.DB, filters []types.Filter, fields FilterFields) (*gorm.DB, error) {
+	for _, filter := range filters {
+		field, ok := fields[filter.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %s", domain.ErrInvalidFilter, filter.Field)
+		}
+
+		condition, err := filterCondition(field, filter)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s[%s]: %v", domain.ErrInvalidFilter, filter.Field, filter.Operator, err)
+		}
+
+		if field.Related != "" {
+			condition = clause.Expr{SQL: field.Related, Vars: []any{condition}}
+		}
+		where = where.Where(condition)
+	}
+	return where, nil
+}
+
+func filterCondition(field FilterField, filter types.Filter) (clause.Expression, error) {
+	column := filterColumn(field.Column)
+
+	if sqlOperator, ok := comparisons[filter.Operator]; ok {
+		if field.Kind == FilterBool && filter.Operator != opEq && filter.Operator != opNe {
+			return nil, errors.New("operator not supported on this field")
+		}
+		value, err := filterValue(field.Kind, filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Expr{SQL: "? " + sqlOperator + " ?", Vars: []any{column, value}}, nil
+	}
+
+	switch filter.Operator {
+	case opLike:
+		if field.Kind != FilterString {
+			return nil, errors.New("like only works on text fields")
+		}
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []any{column, "%" + escapeLike(filter.Value) + "%"}}, nil
+
+	case opIn:
+		parts := strings.Split(filter.Value, ",")
+		values := make([]any, 0, len(parts))
+		for _, part := range parts {
+			value, err := filterValue(field.Kind, strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return clause.Expr{SQL: "? IN ?", Vars: []any{column, values}}, nil
+
+	case opBetween:
+		parts := strings.Split(filter.Value, ",")
+		if len(parts) != 2 || field.Kind == FilterBool {
+			return nil, errors.New("between expects two values separated by a comma")
+		}
+		from, err := filterValue(field.Kind, strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		to, err := filterValue(field.Kind, strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []any{column, from, to}}, nil
+
+	case opNull:
+		isNull, err := strconv.ParseBool(filter.Value)
+		if err != nil {
+			return nil, err
+		}
+		if isNull {
+			return clause.Expr{SQL: "? IS NULL", Vars: []any{column}}, nil
+		}
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []any{column}}, nil
+	}
+
+	return nil, errors.New("unknown operator")
+}
+
+// filterColumn quotes the column, unqualified columns belong to the table being listed
+func filterColumn(name string) clause.Column {
+	if table, column, ok := strings.Cut(name, "."); ok {
+		return clause.Column{Table: table, Name: column}
+	}
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
+
+// filterValue converts the raw query value so postgres compares it with the column type
+func filterValue(kind FilterKind, raw string) (any, error) {
+	switch kind {
+	case FilterNumber:
+		return strconv.ParseFloat(raw, 64)
+	case FilterBool:
+		return strconv.ParseBool(raw)
+	case FilterUUID:
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		return id.String(), nil
+	case FilterTime:
+		if at, err := time.Parse(time.RFC3339, raw); err == nil {
+			return at, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	}
+	return raw, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
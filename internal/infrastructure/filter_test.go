@@ -0,0 +1,119 @@
+package infrastructure
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestFilterCondition(t *testing.T) {
+	id := "0b5f7f8e-4a55-4d43-9c5c-5b3f6f0c2a11"
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		field  FilterField
+		filter types.Filter
+		sql    string
+		vars   []any
+	}{
+		{
+			name:   "number comparison",
+			field:  FilterField{Column: "price", Kind: FilterNumber},
+			filter: types.Filter{Operator: "gte", Value: "9.5"},
+			sql:    "? >= ?",
+			vars:   []any{9.5},
+		},
+		{
+			name:   "like escapes wildcards",
+			field:  FilterField{Column: "name", Kind: FilterString},
+			filter: types.Filter{Operator: "like", Value: `50%_off\`},
+			sql:    "? ILIKE ?",
+			vars:   []any{`%50\%\_off\\%`},
+		},
+		{
+			name:   "in converts every value",
+			field:  FilterField{Column: "id", Kind: FilterUUID},
+			filter: types.Filter{Operator: "in", Value: id + ", " + id},
+			sql:    "? IN ?",
+			vars:   []any{[]any{id, id}},
+		},
+		{
+			name:   "between dates",
+			field:  FilterField{Column: "created_at", Kind: FilterTime},
+			filter: types.Filter{Operator: "between", Value: "2024-03-01,2024-03-01T00:00:00Z"},
+			sql:    "? BETWEEN ? AND ?",
+			vars:   []any{at, at},
+		},
+		{
+			name:   "bool equality",
+			field:  FilterField{Column: "is_deleted", Kind: FilterBool},
+			filter: types.Filter{Operator: "eq", Value: "true"},
+			sql:    "? = ?",
+			vars:   []any{true},
+		},
+		{
+			name:   "null",
+			field:  FilterField{Column: "deleted_at", Kind: FilterTime},
+			filter: types.Filter{Operator: "null", Value: "true"},
+			sql:    "? IS NULL",
+			vars:   []any{},
+		},
+		{
+			name:   "not null",
+			field:  FilterField{Column: "deleted_at", Kind: FilterTime},
+			filter: types.Filter{Operator: "null", Value: "false"},
+			sql:    "? IS NOT NULL",
+			vars:   []any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := filterCondition(tt.field, tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expr, ok := condition.(clause.Expr)
+			if !ok {
+				t.Fatalf("condition is %T, want clause.Expr", condition)
+			}
+			if expr.SQL != tt.sql {
+				t.Errorf("sql is %q, want %q", expr.SQL, tt.sql)
+			}
+			// the first var is always the column
+			if got := expr.Vars[1:]; !reflect.DeepEqual(got, tt.vars) {
+				t.Errorf("vars are %#v, want %#v", got, tt.vars)
+			}
+		})
+	}
+}
+
+func TestFilterConditionRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		field  FilterField
+		filter types.Filter
+	}{
+		{name: "unknown operator", field: FilterField{Column: "name", Kind: FilterString}, filter: types.Filter{Operator: "regex", Value: "a"}},
+		{name: "like on a number", field: FilterField{Column: "price", Kind: FilterNumber}, filter: types.Filter{Operator: "like", Value: "1"}},
+		{name: "ordering a bool", field: FilterField{Column: "is_deleted", Kind: FilterBool}, filter: types.Filter{Operator: "gt", Value: "true"}},
+		{name: "text for a number", field: FilterField{Column: "price", Kind: FilterNumber}, filter: types.Filter{Operator: "eq", Value: "cheap"}},
+		{name: "invalid uuid", field: FilterField{Column: "id", Kind: FilterUUID}, filter: types.Filter{Operator: "in", Value: "1,2"}},
+		{name: "between one value", field: FilterField{Column: "price", Kind: FilterNumber}, filter: types.Filter{Operator: "between", Value: "1"}},
+		{name: "invalid date", field: FilterField{Column: "created_at", Kind: FilterTime}, filter: types.Filter{Operator: "lt", Value: "yesterday"}},
+		{name: "null needs a bool", field: FilterField{Column: "deleted_at", Kind: FilterTime}, filter: types.Filter{Operator: "null", Value: "maybe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := filterCondition(tt.field, tt.filter); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListSpec is what a repository lets list queries touch. Sort maps a public sort name to a column
+// of the listed table, Search and Filters map public names to columns that may live on a related table
+type ListSpec struct {
+	Sort    map[string]string
+	Search  map[string]FilterField
+	Filters FilterFields
+}
+
+type SortKey struct {
+	Name   string
+	Column string
+	Desc   bool
+}
+
+// Sort is an ordered list of sort keys, the row id is always appended as the last tie breaker
+type Sort []SortKey
+
+// String gives the canonical sortBy, e.g. "price,-created_at"
+func (s Sort) String() string {
+	names := make([]string, len(s))
+	for i, key := range s {
+		names[i] = key.Name
+		if key.Desc {
+			names[i] = "-" + key.Name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Order is the direction of the leading key
+func (s Sort) Order() string {
+	if len(s) > 0 && !s[0].Desc {
+		return "asc"
+	}
+	return "desc"
+}
+
+// ParseSort reads sortBy as a comma separated list of sort names, a leading "-" sorts that key
+// descending and keys without a sign use order
+func (s ListSpec) ParseSort(sortBy, order string) (Sort, error) {
+	if order != "asc" && order != "desc" {
+		return nil, fmt.Errorf("%w: order must be asc or desc", domain.ErrInvalidQuery)
+	}
+
+	var sort Sort
+	seen := map[string]bool{}
+	for _, part := range strings.Split(sortBy, ",") {
+		name := strings.TrimSpace(part)
+		desc := order == "desc"
+		if after, ok := strings.CutPrefix(name, "-"); ok {
+			name, desc = after, true
+		} else if after, ok := strings.CutPrefix(name, "+"); ok {
+			name, desc = after, false
+		}
+
+		column, ok := s.Sort[name]
+		if !ok || seen[name] {
+			return nil, fmt.Errorf("%w: cannot sort by %q", domain.ErrInvalidQuery, name)
+		}
+		seen[name] = true
+		sort = append(sort, SortKey{Name: name, Column: column, Desc: desc})
+	}
+	return sort, nil
+}
+
+// ApplySearch adds a case insensitive contains match on the searchable field
+func (s ListSpec) ApplySearch(where *gorm.DB, search, searchField string) (*gorm.DB, error) {
+	if search == "" {
+		return where, nil
+	}
+
+	field, ok := s.Search[searchField]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot search by %q", domain.ErrInvalidQuery, searchField)
+	}
+
+	var condition clause.Expression = clause.Expr{
+		SQL:  "? ILIKE ?",
+		Vars: []any{filterColumn(field.Column), "%" + escapeLike(search) + "%"},
+	}
+	if field.Related != "" {
+		condition = clause.Expr{SQL: field.Related, Vars: []any{condition}}
+	}
+	return where.Where(condition), nil
+}
@@ -0,0 +1,67 @@
+package infrastructure
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+var testListSpec = ListSpec{
+	Sort: map[string]string{
+		"price":      "price",
+		"created_at": "created_at",
+	},
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		sortBy string
+		order  string
+		want   string
+		lead   string
+	}{
+		{name: "single key takes the order", sortBy: "price", order: "asc", want: "price", lead: "asc"},
+		{name: "desc order marks the key", sortBy: "price", order: "desc", want: "-price", lead: "desc"},
+		{name: "signs override the order", sortBy: "-price,+created_at", order: "asc", want: "-price,created_at", lead: "desc"},
+		{name: "spaces around keys are ignored", sortBy: " price , created_at ", order: "asc", want: "price,created_at", lead: "asc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := testListSpec.ParseSort(tt.sortBy, tt.order)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sort.String(); got != tt.want {
+				t.Errorf("sort is %q, want %q", got, tt.want)
+			}
+			if got := sort.Order(); got != tt.lead {
+				t.Errorf("order is %q, want %q", got, tt.lead)
+			}
+		})
+	}
+}
+
+func TestParseSortRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		sortBy string
+		order  string
+	}{
+		{name: "unknown order", sortBy: "price", order: "up"},
+		{name: "unknown key", sortBy: "password", order: "asc"},
+		{name: "repeated key", sortBy: "price,-price", order: "asc"},
+		{name: "empty key", sortBy: "price,", order: "asc"},
+		{name: "raw sql", sortBy: "price; DROP TABLE users", order: "asc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testListSpec.ParseSort(tt.sortBy, tt.order); !errors.Is(err, domain.ErrInvalidQuery) {
+				t.Errorf("error is %v, want ErrInvalidQuery", err)
+			}
+		})
+	}
+}
@@ -9,6 +9,11 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenVersionProvider returns the current token version of a user, tokens carrying an older one are revoked
+type TokenVersionProvider interface {
+	GetTokenVersion(userID string) (int, error)
+}
+
 func ExtractToken(c fiber.Ctx) string {
 	token := c.Cookies("accessToken")
 	if token == "" {
@@ -31,12 +36,29 @@ func SetClaimsToContext(c fiber.Ctx, claims jwt.MapClaims) {
 	c.Locals("role", claims["role"])
 }
 
-func AuthMiddleware(jwtService *utils.JwtService) fiber.Handler {
+// IsTokenRevoked compares the "tv" claim with the user's current token version
+func IsTokenRevoked(claims jwt.MapClaims, tokenVersions TokenVersionProvider) bool {
+	sub, ok := claims["sub"].(string)
+	if !ok || sub == "" {
+		return true
+	}
+
+	current, err := tokenVersions.GetTokenVersion(sub)
+	if err != nil {
+		return true
+	}
+
+	// tokens issued before the claim existed count as version 0
+	tokenVersion, _ := claims["tv"].(float64)
+
+	return int(tokenVersion) != current
+}
+
+func AuthMiddleware(jwtService *utils.JwtService, tokenVersions TokenVersionProvider) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		tokenString := ExtractToken(c)
 		isPublic := c.Locals("public") == true
 
-		// fmt.Printf("Is Public: " + fmt.Sprint(isPublic) + "\n")
 		//token not found: public => next, private => unauthorized
 		if tokenString == "" {
 			if isPublic == true {
@@ -62,6 +84,15 @@ func AuthMiddleware(jwtService *utils.JwtService) fiber.Handler {
 
 		}
 
+		if claims != nil && IsTokenRevoked(claims, tokenVersions) {
+			if isPublic == true {
+				return c.Next()
+			}
+			return c.Status(fiber.StatusUnauthorized).JSON(
+				utils.Error("Token has been revoked", fiber.StatusUnauthorized),
+			)
+		}
+
 		if claims != nil {
 			SetClaimsToContext(c, claims)
 		}
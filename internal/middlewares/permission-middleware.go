@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// PermissionChecker tells whether a role holds at least one of the permissions
+type PermissionChecker interface {
+	HasAnyPermission(role string, permissions ...string) (bool, error)
+}
+
+// PermissionMiddleware lets the request through when the caller's role has any of the permissions
+func PermissionMiddleware(checker PermissionChecker, permissions ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		if role == "" {
+			return c.Status(fiber.StatusForbidden).JSON(
+				utils.Error("Access denied: insufficient permissions", fiber.StatusForbidden))
+		}
+
+		allowed, err := checker.HasAnyPermission(role, permissions...)
+		if err != nil {
+			return err
+		}
+
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(
+				utils.Error("Access denied: insufficient permissions", fiber.StatusForbidden))
+		}
+
+		return c.Next()
+	}
+}
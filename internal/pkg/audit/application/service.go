@@ -0,0 +1,46 @@
+package application
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/audit"
+	httpError "github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/audit/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+)
+
+type AuditService struct {
+	repo *infrastructure.AuditRepository
+}
+
+func NewAuditService(repo *infrastructure.AuditRepository) *AuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+func (s *AuditService) PaginatedEvents(query dtos.AuditQueryDto) (*types.Paginated[*audit.AuditEvent], error) {
+	from, err := parseTime(query.From)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseTime(query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.PaginatedEvents(query.Page, query.Limit, query.ActorID, query.EntityType, query.EntityID, query.Action, from, to, nil)
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, httpError.ErrInvalidQuery
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,21 @@
+package dtos
+
+type AuditQueryDto struct {
+	Page       int    `query:"page" validate:"omitempty,min=1"`
+	Limit      int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	ActorID    string `query:"actorId" validate:"omitempty,uuid"`
+	EntityType string `query:"entityType" validate:"omitempty,max=50"`
+	EntityID   string `query:"entityId" validate:"omitempty,uuid"`
+	Action     string `query:"action" validate:"omitempty,max=50"`
+	From       string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+func (q *AuditQueryDto) ApplyDefaults() {
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.Limit == 0 {
+		q.Limit = 20
+	}
+}
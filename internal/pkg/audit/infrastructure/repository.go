@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/audit"
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	*infrastructure.BaseRepository[*audit.AuditEvent]
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*audit.AuditEvent](db),
+		db:             db,
+	}
+}
+
+// Record stores one event, pass the transaction of the change so both commit or neither does
+func (r *AuditRepository) Record(actorID string, action audit.Action, entityType string, entityID string, details interface{}, tx *gorm.DB) error {
+	event := &audit.AuditEvent{
+		Action:     string(action),
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+
+	if actorID != "" {
+		event.ActorID = &actorID
+	}
+
+	if details != nil {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		event.Details = raw
+	}
+
+	_, err := r.Create(event, tx)
+	return err
+}
+
+// PaginatedEvents lists events newest first, empty filters are ignored
+func (r *AuditRepository) PaginatedEvents(page int, limit int, actorID, entityType, entityID, action string, from, to *time.Time, tx *gorm.DB) (*types.Paginated[*audit.AuditEvent], error) {
+	page, limit = infrastructure.ClampPage(page, limit)
+	where := r.GetDatabase(tx).Model(&audit.AuditEvent{})
+
+	if actorID != "" {
+		where = where.Where("actor_id = ?", actorID)
+	}
+	if entityType != "" {
+		where = where.Where("entity_type = ?", entityType)
+	}
+	if entityID != "" {
+		where = where.Where("entity_id = ?", entityID)
+	}
+	if action != "" {
+		where = where.Where("action = ?", action)
+	}
+	if from != nil {
+		where = where.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		where = where.Where("created_at < ?", *to)
+	}
+
+	var total int64
+	if err := where.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var events []*audit.AuditEvent
+	if err := where.Order("created_at desc").Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	return &types.Paginated[*audit.AuditEvent]{
+		Data:        events,
+		TotalPages:  totalPages,
+		CurrentPage: page,
+		Limit:       limit,
+		Order:       "desc",
+		SortBy:      "created_at",
+		HasPrevious: page > 1,
+		HasNext:     page < totalPages,
+	}, nil
+}
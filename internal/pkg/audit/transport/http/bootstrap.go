@@ -0,0 +1,26 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/config"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/audit/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
+	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+func BootstrapAuditRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig) {
+
+	jwtService := utils.NewJwtService(jwtConfig)
+	auditRepository := infrastructure.NewAuditRepository(db)
+	userRepository := userInfrastructure.NewUserRepository(db)
+	permissionService := permissionApplication.NewPermissionService(permissionInfrastructure.NewPermissionRepository(db), auditRepository)
+	auditService := application.NewAuditService(auditRepository)
+	auditHandler := NewAuditHandler(auditService)
+	auditRouter := NewRouter(auditHandler, jwtService, userRepository, permissionService)
+	auditRouter.SetupRoutes(api)
+}
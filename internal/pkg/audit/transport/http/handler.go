@@ -0,0 +1,39 @@
+package http
+
+import (
+	httpError "github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/audit/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/audit/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type AuditHandler struct {
+	service *application.AuditService
+}
+
+func NewAuditHandler(service *application.AuditService) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+	}
+}
+
+func (h *AuditHandler) PaginatedEvents(c fiber.Ctx) error {
+	var query dtos.AuditQueryDto
+	if err := c.Bind().Query(&query); err != nil {
+		err := httpError.ErrInvalidQuery
+		return err
+	}
+
+	query.ApplyDefaults()
+
+	data, err := h.service.PaginatedEvents(query)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		data, fiber.StatusOK,
+	))
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/middlewares"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type Router struct {
+	handler       *AuditHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
+}
+
+func NewRouter(handler *AuditHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
+	return &Router{
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
+	}
+}
+
+func (r *Router) SetupRoutes(app fiber.Router) {
+	admin := app.Group("/admin")
+
+	admin.Use(middlewares.AuthMiddleware(r.jwtService, r.tokenVersions))
+
+	admin.Get("/audit",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.AuditRead)),
+		r.handler.PaginatedEvents)
+}
@@ -0,0 +1,372 @@
+package application
+
+import (
+	"slices"
+
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/types"
+	orderApplication "github.com/QuangNV23062004/learning-go/internal/pkg/orders/application"
+	orderDomain "github.com/QuangNV23062004/learning-go/internal/pkg/orders/domain"
+	orderDtos "github.com/QuangNV23062004/learning-go/internal/pkg/orders/dtos"
+	productDomain "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
+
+	"gorm.io/gorm"
+)
+
+// CartService keeps one cart per user. Lines are checked against the live stock and price
+// whenever they change but nothing is reserved before checkout
+type CartService struct {
+	repo         *infrastructure.CartRepository
+	productRepo  *productInfrastructure.ProductRepository
+	orderService *orderApplication.OrderService
+}
+
+func NewCartService(repo *infrastructure.CartRepository, productRepo *productInfrastructure.ProductRepository, orderService *orderApplication.OrderService) *CartService {
+	return &CartService{
+		repo:         repo,
+		productRepo:  productRepo,
+		orderService: orderService,
+	}
+}
+
+func (s *CartService) GetCart(sub string) (*types.Cart, error) {
+	return s.loadCart(sub, nil)
+}
+
+// AddItem puts the variant in the cart, adding to the line that already holds it.
+// A product with a single variant may be added without naming the variant
+func (s *CartService) AddItem(itemDto *dtos.AddCartItemDTO, sub string) (*types.Cart, error) {
+	if itemDto.Quantity <= 0 {
+		return nil, domain.ErrInvalidQuantity
+	}
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	var cart *types.Cart
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		variant, err := s.resolveVariant(itemDto.ProductID, itemDto.VariantID, tx)
+		if err != nil {
+			return err
+		}
+
+		item, err := s.repo.AddQuantity(sub, variant.ProductID, variant.ID, itemDto.Quantity, tx)
+		if err != nil {
+			return err
+		}
+
+		cart, err = s.loadCart(sub, tx)
+		if err != nil {
+			return err
+		}
+		return lineError(cart, item.ID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// UpdateItem sets the quantity of a line of the caller's cart
+func (s *CartService) UpdateItem(id string, itemDto *dtos.UpdateCartItemDTO, sub string) (*types.Cart, error) {
+	if itemDto.Quantity <= 0 {
+		return nil, domain.ErrInvalidQuantity
+	}
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	var cart *types.Cart
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		item, err := s.repo.FindItemForUpdate(sub, id, tx)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return domain.ErrCartItemNotFound
+		}
+
+		if err := s.repo.SetQuantity(item.ID, itemDto.Quantity, tx); err != nil {
+			return err
+		}
+
+		cart, err = s.loadCart(sub, tx)
+		if err != nil {
+			return err
+		}
+		return lineError(cart, item.ID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *CartService) RemoveItem(id string, sub string) (*types.Cart, error) {
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	var cart *types.Cart
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		item, err := s.repo.FindItemForUpdate(sub, id, tx)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return domain.ErrCartItemNotFound
+		}
+
+		if err := s.repo.DeleteItem(item.ID, tx); err != nil {
+			return err
+		}
+
+		cart, err = s.loadCart(sub, tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+func (s *CartService) ClearCart(sub string) (*types.Cart, error) {
+	if err := s.repo.DeleteForUser(sub, nil); err != nil {
+		return nil, err
+	}
+	return s.loadCart(sub, nil)
+}
+
+// Checkout turns the cart into a pending order and empties it in one transaction. The cart lines and
+// then the products are locked before the lines are checked, so what passes the check is what gets
+// reserved. When any line has a problem nothing is ordered and every problem is reported
+func (s *CartService) Checkout(sub string) (*orderDomain.Order, error) {
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	var order *orderDomain.Order
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		items, err := s.repo.FindByUserIDForUpdate(sub, tx)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return domain.ErrEmptyCart
+		}
+
+		productIDs := cartProductIDs(items)
+		products, err := s.productRepo.FindByIDsForUpdate(productIDs, false, tx)
+		if err != nil {
+			return err
+		}
+
+		variants, err := s.productRepo.FindVariants(productIDs, tx)
+		if err != nil {
+			return err
+		}
+
+		cart := priceCart(items, products, variants)
+		if !cart.CanCheckout {
+			return &domain.CheckoutError{Problems: lineProblems(cart)}
+		}
+
+		itemDtos := make([]orderDtos.OrderItemDTO, 0, len(items))
+		for _, item := range items {
+			itemDtos = append(itemDtos, orderDtos.OrderItemDTO{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+			})
+		}
+
+		order, err = s.orderService.PlaceOrder(itemDtos, sub, tx)
+		if err != nil {
+			return err
+		}
+
+		return s.repo.DeleteForUser(sub, tx)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// resolveVariant finds the live variant a new cart line points at
+func (s *CartService) resolveVariant(productID string, variantID string, tx *gorm.DB) (*productDomain.ProductVariant, error) {
+	products, err := s.productRepo.FindByIDs([]string{productID}, false, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, domain.ErrProductNotFound
+	}
+
+	variants, err := s.productRepo.FindVariants([]string{productID}, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if variantID == "" {
+		switch len(variants) {
+		case 0:
+			return nil, domain.ErrVariantNotFound
+		case 1:
+			return variants[0], nil
+		default:
+			return nil, domain.ErrVariantRequired
+		}
+	}
+
+	for _, variant := range variants {
+		if variant.ID == variantID {
+			return variant, nil
+		}
+	}
+	return nil, domain.ErrVariantNotFound
+}
+
+// loadCart prices the user's cart with the products as they are now, without locking them
+func (s *CartService) loadCart(sub string, tx *gorm.DB) (*types.Cart, error) {
+	items, err := s.repo.FindByUserID(sub, tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return priceCart(items, nil, nil), nil
+	}
+
+	productIDs := cartProductIDs(items)
+	products, err := s.productRepo.FindByIDs(productIDs, false, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.productRepo.FindVariants(productIDs, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return priceCart(items, products, variants), nil
+}
+
+// priceCart fills in every line from its product and variant and flags the lines that can't be ordered.
+// The first orderable line sets the cart currency, the total only counts orderable lines
+func priceCart(items []*domain.CartItem, products []*productDomain.Product, variants []*productDomain.ProductVariant) *types.Cart {
+	productsByID := make(map[string]*productDomain.Product, len(products))
+	for _, product := range products {
+		productsByID[product.ID] = product
+	}
+
+	variantsByID := make(map[string]*productDomain.ProductVariant, len(variants))
+	for _, variant := range variants {
+		variantsByID[variant.ID] = variant
+	}
+
+	cart := &types.Cart{
+		Items:       make([]*types.CartLine, 0, len(items)),
+		CanCheckout: len(items) > 0,
+	}
+
+	for _, item := range items {
+		line := &types.CartLine{CartItem: item}
+		cart.Items = append(cart.Items, line)
+
+		product, ok := productsByID[item.ProductID]
+		if !ok {
+			line.Problem = enums.ProductUnavailable
+			cart.CanCheckout = false
+			continue
+		}
+		line.ProductName = product.Name
+		line.Currency = product.Currency
+
+		variant, ok := variantsByID[item.VariantID]
+		if !ok || variant.ProductID != product.ID {
+			line.Problem = enums.VariantUnavailable
+			cart.CanCheckout = false
+			continue
+		}
+		line.SKU = variant.SKU
+		line.VariantOptions = variant.Options
+		line.UnitPrice = variant.UnitPrice(product)
+		line.Subtotal = float64(item.Quantity) * line.UnitPrice
+		line.Available = variant.Stock
+
+		switch {
+		case item.Quantity > variant.Stock:
+			line.Problem = enums.InsufficientStock
+		case cart.Currency != "" && cart.Currency != product.Currency:
+			line.Problem = enums.CurrencyMismatch
+		}
+		if line.Problem != "" {
+			cart.CanCheckout = false
+			continue
+		}
+
+		cart.Currency = product.Currency
+		cart.Total += line.Subtotal
+	}
+
+	return cart
+}
+
+// lineError turns the problem of a line the caller just changed into the error to refuse the change with
+func lineError(cart *types.Cart, itemID string) error {
+	for _, line := range cart.Items {
+		if line.ID != itemID {
+			continue
+		}
+		switch line.Problem {
+		case enums.ProductUnavailable:
+			return domain.ErrProductNotFound
+		case enums.VariantUnavailable:
+			return domain.ErrVariantNotFound
+		case enums.InsufficientStock:
+			return domain.ErrNotEnoughStock
+		case enums.CurrencyMismatch:
+			return domain.ErrCurrencyMismatch
+		}
+	}
+	return nil
+}
+
+func lineProblems(cart *types.Cart) []domain.LineProblem {
+	var problems []domain.LineProblem
+	for _, line := range cart.Items {
+		if line.Problem == "" {
+			continue
+		}
+		problems = append(problems, domain.LineProblem{
+			ItemID:    line.ID,
+			ProductID: line.ProductID,
+			VariantID: line.VariantID,
+			Quantity:  line.Quantity,
+			Available: line.Available,
+			Problem:   line.Problem,
+		})
+	}
+	return problems
+}
+
+func cartProductIDs(items []*domain.CartItem) []string {
+	productIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(productIDs, item.ProductID) {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	return productIDs
+}
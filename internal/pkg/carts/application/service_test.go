@@ -0,0 +1,138 @@
+package application
+
+import (
+	"testing"
+
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/enums"
+	productDomain "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+)
+
+func cartItem(id, productID, variantID string, quantity int) *domain.CartItem {
+	return &domain.CartItem{
+		ProductID:  productID,
+		VariantID:  variantID,
+		Quantity:   quantity,
+		BaseEntity: baseDomain.BaseEntity{ID: id},
+	}
+}
+
+func TestPriceCart(t *testing.T) {
+	override := 15.0
+	products := []*productDomain.Product{
+		{Name: "shirt", Price: 10, Currency: "USD", BaseEntity: baseDomain.BaseEntity{ID: "shirt"}},
+		{Name: "mug", Price: 4, Currency: "USD", BaseEntity: baseDomain.BaseEntity{ID: "mug"}},
+		{Name: "poster", Price: 3, Currency: "EUR", BaseEntity: baseDomain.BaseEntity{ID: "poster"}},
+	}
+	variants := []*productDomain.ProductVariant{
+		{ProductID: "shirt", SKU: "shirt-s", Stock: 5, BaseEntity: baseDomain.BaseEntity{ID: "shirt-s"}},
+		{ProductID: "shirt", SKU: "shirt-xl", Price: &override, Stock: 1, BaseEntity: baseDomain.BaseEntity{ID: "shirt-xl"}},
+		{ProductID: "mug", SKU: "mug", Stock: 10, BaseEntity: baseDomain.BaseEntity{ID: "mug-default"}},
+		{ProductID: "poster", SKU: "poster", Stock: 10, BaseEntity: baseDomain.BaseEntity{ID: "poster-default"}},
+	}
+
+	tests := []struct {
+		name        string
+		items       []*domain.CartItem
+		total       float64
+		currency    string
+		canCheckout bool
+		problems    map[string]enums.Problem
+	}{
+		{
+			name:        "empty cart",
+			canCheckout: false,
+		},
+		{
+			name: "orderable lines",
+			items: []*domain.CartItem{
+				cartItem("a", "shirt", "shirt-s", 2),
+				cartItem("b", "shirt", "shirt-xl", 1),
+				cartItem("c", "mug", "mug-default", 3),
+			},
+			total:       2*10 + 15 + 3*4,
+			currency:    "USD",
+			canCheckout: true,
+		},
+		{
+			name: "deleted product",
+			items: []*domain.CartItem{
+				cartItem("a", "gone", "gone-default", 1),
+				cartItem("b", "mug", "mug-default", 1),
+			},
+			total:    4,
+			currency: "USD",
+			problems: map[string]enums.Problem{"a": enums.ProductUnavailable},
+		},
+		{
+			name: "variant of another product",
+			items: []*domain.CartItem{
+				cartItem("a", "mug", "shirt-s", 1),
+			},
+			problems: map[string]enums.Problem{"a": enums.VariantUnavailable},
+		},
+		{
+			name: "more than the stock",
+			items: []*domain.CartItem{
+				cartItem("a", "shirt", "shirt-xl", 2),
+			},
+			problems: map[string]enums.Problem{"a": enums.InsufficientStock},
+		},
+		{
+			name: "first orderable line sets the currency",
+			items: []*domain.CartItem{
+				cartItem("a", "shirt", "shirt-xl", 2),
+				cartItem("b", "poster", "poster-default", 1),
+				cartItem("c", "mug", "mug-default", 1),
+			},
+			total:    3,
+			currency: "EUR",
+			problems: map[string]enums.Problem{"a": enums.InsufficientStock, "c": enums.CurrencyMismatch},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := priceCart(tt.items, products, variants)
+
+			if cart.Total != tt.total {
+				t.Errorf("total is %v, want %v", cart.Total, tt.total)
+			}
+			if cart.Currency != tt.currency {
+				t.Errorf("currency is %q, want %q", cart.Currency, tt.currency)
+			}
+			if cart.CanCheckout != tt.canCheckout {
+				t.Errorf("can checkout is %v, want %v", cart.CanCheckout, tt.canCheckout)
+			}
+			if len(cart.Items) != len(tt.items) {
+				t.Fatalf("%d lines, want %d", len(cart.Items), len(tt.items))
+			}
+			for _, line := range cart.Items {
+				if line.Problem != tt.problems[line.ID] {
+					t.Errorf("line %s has problem %q, want %q", line.ID, line.Problem, tt.problems[line.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestPriceCartFillsLines(t *testing.T) {
+	override := 15.0
+	products := []*productDomain.Product{
+		{Name: "shirt", Price: 10, Currency: "USD", BaseEntity: baseDomain.BaseEntity{ID: "shirt"}},
+	}
+	variants := []*productDomain.ProductVariant{
+		{ProductID: "shirt", SKU: "shirt-xl", Options: map[string]string{"size": "xl"}, Price: &override, Stock: 4, BaseEntity: baseDomain.BaseEntity{ID: "shirt-xl"}},
+	}
+
+	cart := priceCart([]*domain.CartItem{cartItem("a", "shirt", "shirt-xl", 2)}, products, variants)
+	line := cart.Items[0]
+
+	if line.ProductName != "shirt" || line.SKU != "shirt-xl" || line.VariantOptions["size"] != "xl" {
+		t.Errorf("line describes %q %q %v", line.ProductName, line.SKU, line.VariantOptions)
+	}
+	if line.UnitPrice != 15 || line.Subtotal != 30 || line.Available != 4 || line.Currency != "USD" {
+		t.Errorf("line is priced %v x2 = %v %s with %d available", line.UnitPrice, line.Subtotal, line.Currency, line.Available)
+	}
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// CartItem is one line of a user's cart. It points at a variant but holds no stock,
+// stock and price are only checked against the live product until checkout
+type CartItem struct {
+	UserID    string `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_cart_item_variant"`
+	ProductID string `json:"product_id" gorm:"type:uuid;not null;index"`
+	VariantID string `json:"variant_id" gorm:"type:uuid;not null;uniqueIndex:idx_cart_item_variant"`
+	Quantity  int    `json:"quantity" gorm:"not null"`
+	domain.BaseEntity
+}
+
+func (i *CartItem) GetBaseEntity() *domain.BaseEntity {
+	return &i.BaseEntity
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/enums"
+)
+
+var (
+	ErrCartItemNotFound = errors.New("cart item not found")
+	ErrProductNotFound  = errors.New("product not found")
+	ErrVariantNotFound  = errors.New("variant not found for the product")
+	ErrVariantRequired  = errors.New("the product has several variants, variant_id is required")
+	ErrInvalidQuantity  = errors.New("quantity must be greater than zero")
+	ErrNotEnoughStock   = errors.New("not enough stock for the requested quantity")
+	ErrCurrencyMismatch = errors.New("all cart items must use the same currency")
+	ErrEmptyCart        = errors.New("cart is empty")
+	ErrCheckoutBlocked  = errors.New("some cart items cannot be ordered")
+)
+
+// LineProblem is a cart line that stops checkout, Available is the stock left of its variant
+type LineProblem struct {
+	ItemID    string        `json:"item_id"`
+	ProductID string        `json:"product_id"`
+	VariantID string        `json:"variant_id"`
+	Quantity  int           `json:"quantity"`
+	Available int           `json:"available"`
+	Problem   enums.Problem `json:"problem"`
+}
+
+// CheckoutError lists every line that has to be fixed before the cart can be ordered
+type CheckoutError struct {
+	Problems []LineProblem
+}
+
+func (e *CheckoutError) Error() string {
+	return ErrCheckoutBlocked.Error()
+}
+
+func (e *CheckoutError) Unwrap() error {
+	return ErrCheckoutBlocked
+}
+
+func (e *CheckoutError) Details() interface{} {
+	return map[string]interface{}{"problems": e.Problems}
+}
@@ -0,0 +1,9 @@
+package dtos
+
+type AddCartItemDTO struct {
+	ProductID string `json:"product_id" binding:"required,uuid"`
+	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+
+	// may be left out when the product has a single variant
+	VariantID string `json:"variant_id" binding:"omitempty,uuid"`
+}
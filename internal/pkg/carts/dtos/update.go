@@ -0,0 +1,5 @@
+package dtos
+
+type UpdateCartItemDTO struct {
+	Quantity int `json:"quantity" binding:"required,gt=0"`
+}
@@ -0,0 +1,11 @@
+package enums
+
+// Problem is why a cart line cannot be ordered as it is
+type Problem string
+
+const (
+	ProductUnavailable Problem = "product_unavailable"
+	VariantUnavailable Problem = "variant_unavailable"
+	InsufficientStock  Problem = "insufficient_stock"
+	CurrencyMismatch   Problem = "currency_mismatch"
+)
@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartRepository struct {
+	*infrastructure.BaseRepository[*domain.CartItem]
+	db *gorm.DB
+}
+
+func NewCartRepository(db *gorm.DB) *CartRepository {
+	return &CartRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.CartItem](db),
+		db:             db,
+	}
+}
+
+// FindByUserID returns the cart of the user in the order the lines were added
+func (r *CartRepository) FindByUserID(userID string, tx *gorm.DB) ([]*domain.CartItem, error) {
+	var items []*domain.CartItem
+	err := r.GetDatabase(tx).Where("user_id = ?", userID).Order("created_at asc, id asc").Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FindByUserIDForUpdate locks the cart lines until the transaction ends, so a cart is checked out once.
+// The lines are locked before the products, the same order every cart write takes them in
+func (r *CartRepository) FindByUserIDForUpdate(userID string, tx *gorm.DB) ([]*domain.CartItem, error) {
+	var items []*domain.CartItem
+	err := r.GetDatabase(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		Order("created_at asc, id asc").
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FindItemForUpdate locks one line of the user's cart, nil when the user has no such line
+func (r *CartRepository) FindItemForUpdate(userID string, id string, tx *gorm.DB) (*domain.CartItem, error) {
+	var items []*domain.CartItem
+	err := r.GetDatabase(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Limit(1).
+		Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// AddQuantity puts the variant in the cart or adds to the line already holding it, the line is returned
+// with the new quantity. Two adds of the same variant at once both land on the one line
+func (r *CartRepository) AddQuantity(userID string, productID string, variantID string, quantity int, tx *gorm.DB) (*domain.CartItem, error) {
+	now := time.Now()
+	db := r.GetDatabase(tx)
+
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("cart_items.quantity + excluded.quantity"),
+			"updated_at": now,
+			"version":    gorm.Expr("cart_items.version + 1"),
+		}),
+	}).Create(&domain.CartItem{
+		UserID:    userID,
+		ProductID: productID,
+		VariantID: variantID,
+		Quantity:  quantity,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	item := &domain.CartItem{}
+	if err := db.Where("user_id = ? AND variant_id = ?", userID, variantID).First(item).Error; err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (r *CartRepository) SetQuantity(id string, quantity int, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.CartItem{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"quantity": quantity,
+			"version":  gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *CartRepository) DeleteItem(id string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("id = ?", id).Delete(&domain.CartItem{}).Error
+}
+
+// DeleteForUser empties the user's cart
+func (r *CartRepository) DeleteForUser(userID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("user_id = ?", userID).Delete(&domain.CartItem{}).Error
+}
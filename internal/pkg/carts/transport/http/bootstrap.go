@@ -0,0 +1,32 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/config"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/infrastructure"
+	orderApplication "github.com/QuangNV23062004/learning-go/internal/pkg/orders/application"
+	orderInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/orders/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
+	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
+	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+func BootstrapCartRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig, orderConfig *config.OrderConfig) {
+
+	jwtService := utils.NewJwtService(jwtConfig)
+	repo := infrastructure.NewCartRepository(db)
+	userRepository := userInfrastructure.NewUserRepository(db)
+	productRepository := productInfrastructure.NewProductRepository(db)
+	permissionService := permissionApplication.NewPermissionService(permissionInfrastructure.NewPermissionRepository(db), auditInfrastructure.NewAuditRepository(db))
+	orderService := orderApplication.NewOrderService(orderInfrastructure.NewOrderRepository(db), userRepository, productRepository, permissionService, orderConfig)
+	cartService := application.NewCartService(repo, productRepository, orderService)
+	cartHandler := NewCartHandler(cartService)
+	cartRouter := NewRouter(cartHandler, jwtService, userRepository, permissionService)
+	cartRouter.SetupRoutes(api)
+}
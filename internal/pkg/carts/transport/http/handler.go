@@ -0,0 +1,114 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type CartHandler struct {
+	service *application.CartService
+}
+
+func NewCartHandler(service *application.CartService) *CartHandler {
+	return &CartHandler{
+		service: service,
+	}
+}
+
+func (h *CartHandler) GetCart(c fiber.Ctx) error {
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.service.GetCart(sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(cart, fiber.StatusOK))
+}
+
+func (h *CartHandler) AddCartItem(c fiber.Ctx) error {
+	var itemDto dtos.AddCartItemDTO
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	if err := c.Bind().Body(&itemDto); err != nil {
+		return http.ErrInvalidBody
+	}
+
+	cart, err := h.service.AddItem(&itemDto, sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(cart, fiber.StatusOK))
+}
+
+func (h *CartHandler) UpdateCartItem(c fiber.Ctx) error {
+	var itemDto dtos.UpdateCartItemDTO
+	id := c.Params("itemId")
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	if err := c.Bind().Body(&itemDto); err != nil {
+		return http.ErrInvalidBody
+	}
+
+	cart, err := h.service.UpdateItem(id, &itemDto, sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(cart, fiber.StatusOK))
+}
+
+func (h *CartHandler) RemoveCartItem(c fiber.Ctx) error {
+	id := c.Params("itemId")
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.service.RemoveItem(id, sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(cart, fiber.StatusOK))
+}
+
+func (h *CartHandler) ClearCart(c fiber.Ctx) error {
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	cart, err := h.service.ClearCart(sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(cart, fiber.StatusOK))
+}
+
+func (h *CartHandler) Checkout(c fiber.Ctx) error {
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	order, err := h.service.Checkout(sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(utils.Success(order, fiber.StatusCreated))
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/middlewares"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type Router struct {
+	handler       *CartHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
+}
+
+func NewRouter(handler *CartHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
+	return &Router{
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
+	}
+}
+
+// every route works on the caller's own cart
+func (r *Router) SetupRoutes(appGroup fiber.Router) {
+	cartGroup := appGroup.Group("/cart")
+	cartGroup.Use(
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.CartManage)),
+	)
+
+	cartGroup.Get("/", r.handler.GetCart)
+	cartGroup.Post("/items", r.handler.AddCartItem)
+	cartGroup.Patch("/items/:itemId", r.handler.UpdateCartItem)
+	cartGroup.Delete("/items/:itemId", r.handler.RemoveCartItem)
+	cartGroup.Delete("/", r.handler.ClearCart)
+
+	// checkout places an order, so it also needs the right to order
+	cartGroup.Post("/checkout",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersCreate)),
+		r.handler.Checkout)
+}
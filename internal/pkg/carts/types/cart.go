@@ -0,0 +1,29 @@
+package types
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/carts/enums"
+)
+
+// Cart is the caller's cart priced with the current product prices, nothing in it is reserved
+type Cart struct {
+	Items    []*CartLine `json:"items"`
+	Total    float64     `json:"total"`
+	Currency string      `json:"currency,omitempty"`
+
+	// false while any line has a problem, see CartLine.Problem
+	CanCheckout bool `json:"can_checkout"`
+}
+
+// CartLine is a cart item with what the product looks like right now
+type CartLine struct {
+	*domain.CartItem
+	ProductName    string            `json:"product_name,omitempty"`
+	SKU            string            `json:"sku,omitempty"`
+	VariantOptions map[string]string `json:"variant_options,omitempty"`
+	UnitPrice      float64           `json:"unit_price"`
+	Currency       string            `json:"currency,omitempty"`
+	Subtotal       float64           `json:"subtotal"`
+	Available      int               `json:"available"`
+	Problem        enums.Problem     `json:"problem,omitempty"`
+}
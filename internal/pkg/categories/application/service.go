@@ -0,0 +1,224 @@
+package application
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/infrastructure"
+
+	"gorm.io/gorm"
+)
+
+type CategoryService struct {
+	repo *infrastructure.CategoryRepository
+}
+
+func NewCategoryService(repo *infrastructure.CategoryRepository) *CategoryService {
+	return &CategoryService{
+		repo: repo,
+	}
+}
+
+// Tree nests every category under its parent, siblings are sorted by name
+func (s *CategoryService) Tree() ([]*domain.CategoryNode, error) {
+	categories, err := s.repo.FindAllOrdered(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*domain.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &domain.CategoryNode{Category: category, Children: []*domain.CategoryNode{}}
+	}
+
+	roots := []*domain.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+func (s *CategoryService) GetCategory(id string) (*domain.Category, error) {
+	category, err := s.repo.FindByID(id, false, nil)
+	if category == nil {
+		return nil, domain.ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *CategoryService) CreateCategory(dto *dtos.CreateCategoryDTO, sub string) (*domain.Category, error) {
+	slug := slugify(dto.Slug)
+	if dto.Slug == "" {
+		slug = slugify(dto.Name)
+	}
+	if slug == "" {
+		return nil, domain.ErrInvalidSlug
+	}
+
+	var created *domain.Category
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureSlugFree(slug, "", tx); err != nil {
+			return err
+		}
+
+		parentID, err := s.resolveParent(dto.ParentID, "", tx)
+		if err != nil {
+			return err
+		}
+
+		created, err = s.repo.Create(&domain.Category{
+			Name:     strings.TrimSpace(dto.Name),
+			Slug:     slug,
+			ParentID: parentID,
+		}, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *CategoryService) UpdateCategory(id string, dto *dtos.UpdateCategoryDTO, sub string, version int) (*domain.Category, error) {
+	var updated *domain.Category
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		category, err := s.repo.FindByID(id, false, tx)
+		if category == nil {
+			return domain.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if !category.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		if dto.Name != "" {
+			category.Name = strings.TrimSpace(dto.Name)
+		}
+
+		if dto.Slug != "" {
+			slug := slugify(dto.Slug)
+			if slug == "" {
+				return domain.ErrInvalidSlug
+			}
+			if err := s.ensureSlugFree(slug, id, tx); err != nil {
+				return err
+			}
+			category.Slug = slug
+		}
+
+		if dto.ParentID != nil {
+			category.ParentID, err = s.resolveParent(dto.ParentID, id, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		updated, err = s.repo.Update(category, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// a category with children has to be emptied first, its products simply lose the category
+func (s *CategoryService) DeleteCategory(id string, sub string, version int) (bool, error) {
+	var deleted bool
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		category, err := s.repo.FindByID(id, false, tx)
+		if category == nil {
+			return domain.ErrCategoryNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if !category.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		hasChildren, err := s.repo.HasChildren(id, tx)
+		if err != nil {
+			return err
+		}
+		if hasChildren {
+			return domain.ErrCategoryHasChildren
+		}
+
+		if err := s.repo.UnlinkProducts(id, tx); err != nil {
+			return err
+		}
+
+		deleted, err = s.repo.HardDelete(id, tx)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted, nil
+}
+
+func (s *CategoryService) ensureSlugFree(slug string, exceptID string, tx *gorm.DB) error {
+	taken, err := s.repo.SlugTaken(slug, exceptID, tx)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return domain.ErrSlugTaken
+	}
+	return nil
+}
+
+// resolveParent checks the requested parent exists, an empty id means the root. When moving
+// categoryID the parent may not sit inside its own subtree
+func (s *CategoryService) resolveParent(parentID *string, categoryID string, tx *gorm.DB) (*string, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+
+	parent, err := s.repo.FindByID(*parentID, false, tx)
+	if parent == nil || err != nil {
+		return nil, domain.ErrParentCategoryNotFound
+	}
+
+	if categoryID != "" {
+		subtree, err := s.repo.SubtreeIDs(categoryID, tx)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(subtree, parent.ID) {
+			return nil, domain.ErrCategoryCycle
+		}
+	}
+	return &parent.ID, nil
+}
+
+// slugify lower cases the text and joins its words with dashes
+func slugify(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
@@ -0,0 +1,23 @@
+package application
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Shoes", want: "shoes"},
+		{text: "  Men's Running Shoes ", want: "men-s-running-shoes"},
+		{text: "TV & Audio", want: "tv-audio"},
+		{text: "USB-C / 3.1", want: "usb-c-3-1"},
+		{text: "Đồ Gia Dụng", want: "đồ-gia-dụng"},
+		{text: "--", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := slugify(tt.text); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// Category is a node of the catalog tree, root categories have no parent
+type Category struct {
+	Name     string  `json:"name" gorm:"not null"`
+	Slug     string  `json:"slug" gorm:"not null;uniqueIndex"`
+	ParentID *string `json:"parent_id" gorm:"type:uuid;index"`
+	domain.BaseEntity
+}
+
+func (c *Category) GetBaseEntity() *domain.BaseEntity {
+	return &c.BaseEntity
+}
+
+func (c *Category) AuditType() string {
+	return "category"
+}
+
+// CategoryNode is a category with its children, the shape navigation menus need
+type CategoryNode struct {
+	*Category
+	Children []*CategoryNode `json:"children"`
+}
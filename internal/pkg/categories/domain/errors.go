@@ -0,0 +1,12 @@
+package domain
+
+import "errors"
+
+var (
+	ErrCategoryNotFound       = errors.New("category not found")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	ErrCategoryCycle          = errors.New("a category cannot be moved under itself or one of its children")
+	ErrCategoryHasChildren    = errors.New("category still has child categories")
+	ErrSlugTaken              = errors.New("category slug already in use")
+	ErrInvalidSlug            = errors.New("category slug must contain letters or digits")
+)
@@ -0,0 +1,7 @@
+package dtos
+
+type CreateCategoryDTO struct {
+	Name     string  `json:"name" binding:"required"`
+	Slug     string  `json:"slug" binding:"omitempty"`
+	ParentID *string `json:"parent_id" binding:"omitempty,uuid"`
+}
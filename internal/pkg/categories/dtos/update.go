@@ -0,0 +1,8 @@
+package dtos
+
+// ParentID left out keeps the parent, an empty string moves the category to the root
+type UpdateCategoryDTO struct {
+	Name     string  `json:"name" binding:"omitempty"`
+	Slug     string  `json:"slug" binding:"omitempty"`
+	ParentID *string `json:"parent_id" binding:"omitempty"`
+}
@@ -0,0 +1,72 @@
+package infrastructure
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/domain"
+	productDomain "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+
+	"gorm.io/gorm"
+)
+
+type CategoryRepository struct {
+	*infrastructure.BaseRepository[*domain.Category]
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.Category](db),
+		db:             db,
+	}
+}
+
+func (r *CategoryRepository) FindAllOrdered(tx *gorm.DB) ([]*domain.Category, error) {
+	var categories []*domain.Category
+	if err := r.GetDatabase(tx).Where("is_deleted = ?", false).Order("name asc").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// SlugTaken reports whether another category already uses the slug
+func (r *CategoryRepository) SlugTaken(slug string, exceptID string, tx *gorm.DB) (bool, error) {
+	var count int64
+	where := r.GetDatabase(tx).Model(&domain.Category{}).Where("slug = ?", slug)
+	if exceptID != "" {
+		where = where.Where("id <> ?", exceptID)
+	}
+	if err := where.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *CategoryRepository) HasChildren(id string, tx *gorm.DB) (bool, error) {
+	var count int64
+	err := r.GetDatabase(tx).Model(&domain.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SubtreeIDs returns the category and every category below it
+func (r *CategoryRepository) SubtreeIDs(id string, tx *gorm.DB) ([]string, error) {
+	var ids []string
+	err := r.GetDatabase(tx).Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+		)
+		SELECT id FROM subtree`, id).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// UnlinkProducts removes the category from every product it was assigned to
+func (r *CategoryRepository) UnlinkProducts(id string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("category_id = ?", id).Delete(&productDomain.ProductCategory{}).Error
+}
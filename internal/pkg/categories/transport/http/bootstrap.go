@@ -0,0 +1,27 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/config"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
+	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+func BootstrapCategoryRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig) {
+
+	jwtService := utils.NewJwtService(jwtConfig)
+	categoryRepository := infrastructure.NewCategoryRepository(db)
+	userRepository := userInfrastructure.NewUserRepository(db)
+	permissionService := permissionApplication.NewPermissionService(permissionInfrastructure.NewPermissionRepository(db), auditInfrastructure.NewAuditRepository(db))
+	categoryService := application.NewCategoryService(categoryRepository)
+	categoryHandler := NewCategoryHandler(categoryService)
+	categoryRouter := NewRouter(categoryHandler, jwtService, userRepository, permissionService)
+	categoryRouter.SetupRoutes(api)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	httpError "github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/categories/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type CategoryHandler struct {
+	service *application.CategoryService
+}
+
+func NewCategoryHandler(service *application.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		service: service,
+	}
+}
+
+func (h *CategoryHandler) GetTree(c fiber.Ctx) error {
+	tree, err := h.service.Tree()
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		tree, fiber.StatusOK,
+	))
+}
+
+func (h *CategoryHandler) GetCategory(c fiber.Ctx) error {
+	category, err := h.service.GetCategory(c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		category, fiber.StatusOK,
+	))
+}
+
+func (h *CategoryHandler) CreateCategory(c fiber.Ctx) error {
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.CreateCategoryDTO
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	category, err := h.service.CreateCategory(&body, sub)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(utils.Success(
+		category, fiber.StatusCreated,
+	))
+}
+
+func (h *CategoryHandler) UpdateCategory(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.UpdateCategoryDTO
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	category, err := h.service.UpdateCategory(id, &body, sub, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		category, fiber.StatusOK,
+	))
+}
+
+func (h *CategoryHandler) DeleteCategory(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := h.service.DeleteCategory(id, sub, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		deleted, fiber.StatusOK,
+	))
+}
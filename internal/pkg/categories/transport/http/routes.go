@@ -0,0 +1,44 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/middlewares"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type Router struct {
+	handler       *CategoryHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
+}
+
+func NewRouter(handler *CategoryHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
+	return &Router{
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
+	}
+}
+
+func (r *Router) SetupRoutes(app fiber.Router) {
+	category := app.Group("/categories")
+
+	category.Get("/", r.handler.GetTree)
+
+	category.Get("/:id", r.handler.GetCategory)
+
+	category.Use(
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.CategoriesManage)),
+	)
+
+	category.Post("/", r.handler.CreateCategory)
+
+	category.Patch("/:id", r.handler.UpdateCategory)
+
+	category.Delete("/:id", r.handler.DeleteCategory)
+}
@@ -1,19 +1,29 @@
 package application
 
 import (
+	"context"
 	"log"
+	"slices"
+	"sort"
+	"time"
 
+	"github.com/QuangNV23062004/learning-go/internal/config"
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/domain"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/enums"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionEnums "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
 	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
-	roleEnums "github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
 	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/types"
 
 	orderType "github.com/QuangNV23062004/learning-go/internal/pkg/orders/types"
 	productDomain "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -21,21 +31,25 @@ type OrderService struct {
 	repo        *infrastructure.OrderRepository
 	userRepo    *userInfrastructure.UserRepository
 	productRepo *productInfrastructure.ProductRepository
+	permissions *permissionApplication.PermissionService
+	orderConfig *config.OrderConfig
 }
 
-func NewOrderService(repo *infrastructure.OrderRepository, userRepo *userInfrastructure.UserRepository, productRepo *productInfrastructure.ProductRepository) *OrderService {
+func NewOrderService(repo *infrastructure.OrderRepository, userRepo *userInfrastructure.UserRepository, productRepo *productInfrastructure.ProductRepository, permissions *permissionApplication.PermissionService, orderConfig *config.OrderConfig) *OrderService {
 	return &OrderService{
 		repo:        repo,
 		userRepo:    userRepo,
 		productRepo: productRepo,
+		permissions: permissions,
+		orderConfig: orderConfig,
 	}
 }
 
-// only admin and owner can see order
+// only the owner or a role with orders:read:any can see the order
 func (s *OrderService) FindOrderByID(id string, includeDeleted bool, role string, sub string) (*orderType.OrderResponse, error) {
 
 	safeIncludeDeleted := false
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.OrdersReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -48,7 +62,7 @@ func (s *OrderService) FindOrderByID(id string, includeDeleted bool, role string
 		return nil, domain.ErrOrderNotFound
 	}
 
-	if role != string(roleEnums.Admin) && order.UserID != sub {
+	if order.UserID != sub && !s.permissions.HasPermission(role, permissionEnums.OrdersReadAny) {
 		return nil, domain.ErrNotAllowed
 	}
 
@@ -59,10 +73,10 @@ func (s *OrderService) FindOrderByID(id string, includeDeleted bool, role string
 	return order, nil
 }
 
-// only admin and owner can see orders
+// only the owner or a role with orders:read:any can see the orders
 func (s *OrderService) FindOrdersByUserID(userID string, includeDeleted bool, sub string, role string) ([]*orderType.OrderResponse, error) {
 
-	if role != string(roleEnums.Admin) && sub != userID {
+	if sub != userID && !s.permissions.HasPermission(role, permissionEnums.OrdersReadAny) {
 		return nil, domain.ErrNotAllowed
 	}
 
@@ -77,7 +91,7 @@ func (s *OrderService) FindOrdersByUserID(userID string, includeDeleted bool, su
 
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.OrdersReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -93,10 +107,10 @@ func (s *OrderService) FindOrdersByUserID(userID string, includeDeleted bool, su
 	return orders, nil
 }
 
-// only admin and owner can see paginated orders
-func (s *OrderService) FindPaginatedOrdersByUserID(userID string, page int, limit int, search string, searchField string, order string, sortBy string, includeDeleted bool, sub string, role string) (*types.Paginated[*orderType.OrderResponse], error) {
+// only the owner or a role with orders:read:any can see the orders
+func (s *OrderService) FindPaginatedOrdersByUserID(userID string, page int, limit int, cursor string, search string, searchField string, order string, sortBy string, filters []types.Filter, includeDeleted bool, sub string, role string) (*types.Paginated[*orderType.OrderResponse], error) {
 
-	if role != string(roleEnums.Admin) && sub != userID {
+	if sub != userID && !s.permissions.HasPermission(role, permissionEnums.OrdersReadAny) {
 		return nil, domain.ErrNotAllowed
 	}
 
@@ -111,11 +125,11 @@ func (s *OrderService) FindPaginatedOrdersByUserID(userID string, page int, limi
 
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.OrdersReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
-	paginatedOrders, err := s.repo.FindOrdersByUserIDPaginatedWithOptions(userID, page, limit, search, searchField, order, sortBy, safeIncludeDeleted, types.OrderOptions{
+	paginatedOrders, err := s.repo.FindOrdersByUserIDPaginatedWithOptions(userID, page, limit, cursor, search, searchField, order, sortBy, filters, safeIncludeDeleted, types.OrderOptions{
 		WithUser:    true,
 		WithProduct: true,
 	})
@@ -127,10 +141,10 @@ func (s *OrderService) FindPaginatedOrdersByUserID(userID string, page int, limi
 	return paginatedOrders, nil
 }
 
-// only admin can see all orders
+// the route requires orders:read:any
 func (s *OrderService) FindAllOrders(includeDeleted bool, role string) ([]*orderType.OrderResponse, error) {
 	safeIncludeDeleted := false
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.OrdersReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -146,15 +160,15 @@ func (s *OrderService) FindAllOrders(includeDeleted bool, role string) ([]*order
 	return orders, nil
 }
 
-// only admin can see paginated all orders
-func (s *OrderService) Paginated(page int, limit int, search string, searchField string, order string, sortBy string, includeDeleted bool, role string) (*types.Paginated[*orderType.OrderResponse], error) {
+// the route requires orders:read:any
+func (s *OrderService) Paginated(page int, limit int, cursor string, search string, searchField string, order string, sortBy string, filters []types.Filter, includeDeleted bool, role string) (*types.Paginated[*orderType.OrderResponse], error) {
 	safeIncludeDeleted := false
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.OrdersReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 
 	}
 
-	paginatedOrders, err := s.repo.PaginatedWithOptions(page, limit, search, searchField, order, sortBy, safeIncludeDeleted, types.OrderOptions{
+	paginatedOrders, err := s.repo.PaginatedWithOptions(page, limit, cursor, search, searchField, order, sortBy, filters, safeIncludeDeleted, types.OrderOptions{
 		WithUser:    true,
 		WithProduct: true,
 	})
@@ -166,75 +180,75 @@ func (s *OrderService) Paginated(page int, limit int, search string, searchField
 }
 
 func (s *OrderService) Create(orderDto *dtos.CreateOrderDTO, sub string) (*domain.Order, error) {
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 
 	var createdOrder *domain.Order
 
 	err := db.Transaction(func(tx *gorm.DB) error {
-		user, err := s.userRepo.FindByID(sub, false, tx)
-		if user == nil {
-			return domain.ErrUserNotFound
-		}
-
-		if err != nil {
-			return err
-		}
-
-		product, err := s.productRepo.FindByID(orderDto.ProductID, false, tx)
-		if product == nil {
-			return domain.ErrProductNotFound
-		}
+		var err error
+		createdOrder, err = s.PlaceOrder(orderDto.GetItems(), sub, tx)
+		return err
+	})
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		if product.Stock < orderDto.Quantity {
-			return domain.ErrInsufficientStock
-		}
+	return createdOrder, nil
+}
 
-		orderData := &domain.Order{
-			UserID:    sub,
-			ProductID: orderDto.ProductID,
-			Quantity:  orderDto.Quantity,
-			Total:     float64(orderDto.Quantity) * product.Price,
-		}
+// PlaceOrder creates a pending order for the user and reserves its stock inside tx,
+// so callers such as the cart checkout can place the order together with their own writes
+func (s *OrderService) PlaceOrder(itemDtos []dtos.OrderItemDTO, sub string, tx *gorm.DB) (*domain.Order, error) {
+	user, err := s.userRepo.FindByID(sub, false, tx)
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
 
-		createdOrder, err = s.repo.Create(orderData, tx)
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Decrease product stock
-		product.Stock -= orderDto.Quantity
-		_, err = s.productRepo.Update(product, tx)
-		if err != nil {
-			return err
-		}
-		log.Print(product)
+	reservedUntil, err := s.reservationDeadline()
+	if err != nil {
+		return nil, err
+	}
 
-		return nil
-	})
+	// the id is picked up front so the stock movements can point at the order
+	orderID := uuid.NewString()
 
+	// reserve stock for every line in the same transaction
+	items, total, err := s.reserveItems(orderID, itemDtos, nil, tx)
 	if err != nil {
 		return nil, err
 	}
 
-	return createdOrder, nil
+	orderData := &domain.Order{
+		UserID:   sub,
+		Total:    total,
+		Currency: items[0].Currency,
+		Status:   string(enums.Pending),
+		Items:    items,
+
+		ReservedUntil: &reservedUntil,
+	}
+	orderData.ID = orderID
+
+	return s.repo.Create(orderData, tx)
 }
 
-// The api will handle two update scenarios, but irl product change should be rare if not impossible
-// case 1: change product => restock old product, decrease new product stock
-// case 2: change quantity only => adjust stock accordingly
-func (s *OrderService) Update(id string, orderDto *dtos.UpdateOrderDTO, sub string, role string) (*domain.Order, error) {
+// Update replaces the order lines: stock of the old lines is released first,
+// so keeping the same variant only needs the quantity difference to be available.
+// Lines of variants already on the order keep the price they were ordered at
+func (s *OrderService) Update(id string, orderDto *dtos.UpdateOrderDTO, sub string, role string, version int) (*domain.Order, error) {
 
 	var updated *domain.Order
 
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		//check order
-		order, err := s.repo.FindByID(id, false, tx)
+		order, err := s.repo.FindByIDForUpdate(id, false, tx)
 		if order == nil {
 			return domain.ErrOrderNotFound
 		}
@@ -243,16 +257,23 @@ func (s *OrderService) Update(id string, orderDto *dtos.UpdateOrderDTO, sub stri
 			return err
 		}
 
-		//flags
-		changeProductFlag := false
+		// only the owner or a role allowed to edit any order
+		if order.UserID != sub && !s.permissions.HasPermission(role, permissionEnums.OrdersUpdateAny) {
+			return domain.ErrNotAllowed
+		}
+
+		if !order.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
 
-		if order.ProductID != orderDto.ProductID {
-			changeProductFlag = true
+		// lines are fixed once the order leaves pending
+		if enums.Status(order.Status) != enums.Pending {
+			return domain.ErrOrderNotEditable
 		}
 
-		// only admin and owner can update order
-		if role != string(roleEnums.Admin) && order.UserID != sub {
-			return domain.ErrNotAllowed
+		// the sweeper is about to release the stock, editing must not bring the order back
+		if order.ReservationExpired(time.Now()) {
+			return domain.ErrReservationExpired
 		}
 
 		//user check
@@ -265,67 +286,50 @@ func (s *OrderService) Update(id string, orderDto *dtos.UpdateOrderDTO, sub stri
 			return err
 		}
 
-		//product check
-		product, err := s.productRepo.FindByID(orderDto.ProductID, false, tx)
-		if product == nil {
-			return domain.ErrProductNotFound
-		}
-
+		//restock old lines
+		oldItems, err := s.repo.FindItemsByOrderID(order.ID, tx)
 		if err != nil {
 			return err
 		}
 
-		//check & restock old product
-		var oldProduct *productDomain.Product = nil
-		if changeProductFlag {
-			oldProduct, err = s.productRepo.FindByID(order.ProductID, true, tx)
-			if oldProduct == nil {
-				return domain.ErrProductNotFound
-			}
-
-			if err != nil {
-				return err
-			}
-
+		if err := s.releaseItems(oldItems, tx); err != nil {
+			return err
 		}
 
-		if oldProduct != nil {
-			oldProduct.Stock += order.Quantity
-			_, err = s.productRepo.Update(oldProduct, tx)
-			if err != nil {
-				return err
-			}
+		//reserve new lines, variants already on the order keep their snapshot price
+		items, total, err := s.reserveItems(order.ID, orderDto.GetItems(), oldItems, tx)
+		if err != nil {
+			return err
 		}
 
-		availableStock := product.Stock
+		if err := s.repo.DeleteItemsByOrderID(order.ID, tx); err != nil {
+			return err
+		}
 
-		//if same product => account current order quantity, else ignore
-		if !changeProductFlag {
-			availableStock = product.Stock + order.Quantity
+		for i := range items {
+			items[i].OrderID = order.ID
 		}
 
-		if availableStock < orderDto.Quantity {
-			return domain.ErrInsufficientStock
+		if err := s.repo.CreateItems(items, tx); err != nil {
+			return err
 		}
 
-		product.Stock = availableStock - orderDto.Quantity
-		_, err = s.productRepo.Update(product, tx)
+		// the new lines are held for a full reservation period
+		reservedUntil, err := s.reservationDeadline()
 		if err != nil {
 			return err
 		}
 
-		if orderDto.ProductID != "" {
-			order.ProductID = orderDto.ProductID
-		}
-		if orderDto.Quantity != 0 {
-			order.Quantity = orderDto.Quantity
-		}
-		order.Total = float64(orderDto.Quantity) * product.Price
+		order.Total = total
+		order.Currency = items[0].Currency
+		order.ReservedUntil = &reservedUntil
 		updated, err = s.repo.Update(order, tx)
 		if err != nil {
 			return err
 		}
 
+		updated.Items = items
+
 		return nil
 	})
 
@@ -337,15 +341,16 @@ func (s *OrderService) Update(id string, orderDto *dtos.UpdateOrderDTO, sub stri
 
 }
 
-func (s *OrderService) Delete(id string, sub string, role string) (bool, error) {
+// Delete hides the order, an order that still holds stock is cancelled first
+func (s *OrderService) Delete(id string, sub string, role string, version int) (bool, error) {
 
 	var deleted bool
 
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		//check order
-		order, err := s.repo.FindByID(id, false, tx)
+		order, err := s.repo.FindByIDForUpdate(id, false, tx)
 		if order == nil {
 			return domain.ErrOrderNotFound
 		}
@@ -354,21 +359,19 @@ func (s *OrderService) Delete(id string, sub string, role string) (bool, error)
 			return err
 		}
 
-		// only admin and owner can delete order
-		if role != string(roleEnums.Admin) && order.UserID != sub {
+		// only the owner or a role allowed to delete any order
+		if order.UserID != sub && !s.permissions.HasPermission(role, permissionEnums.OrdersDeleteAny) {
 			return domain.ErrNotAllowed
 		}
 
-		//restock product
-		product, err := s.productRepo.FindByID(order.ProductID, true, tx)
-		if err != nil {
-			return err
+		if !order.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
 		}
 
-		product.Stock += order.Quantity
-		_, err = s.productRepo.Update(product, tx)
-		if err != nil {
-			return err
+		if enums.Status(order.Status).CanTransitionTo(enums.Cancelled) {
+			if _, err := s.transition(order, enums.Cancelled, tx); err != nil {
+				return err
+			}
 		}
 
 		deleted, err = s.repo.Delete(id, tx)
@@ -386,14 +389,15 @@ func (s *OrderService) Delete(id string, sub string, role string) (bool, error)
 	return deleted, nil
 }
 
-func (s *OrderService) Restore(id string) (bool, error) {
+// Restore only brings the order back, its status and stock are left as they are
+func (s *OrderService) Restore(id string, sub string, version int) (bool, error) {
 	var restored bool
 
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		order, err := s.repo.FindByID(id, true, tx)
-		if order != nil {
+		if order == nil {
 			return domain.ErrOrderNotFound
 		}
 
@@ -401,31 +405,60 @@ func (s *OrderService) Restore(id string) (bool, error) {
 			return err
 		}
 
-		product, err := s.productRepo.FindByID(order.ProductID, true, tx)
-		if product == nil {
-			return domain.ErrProductNotFound
+		if !order.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
 		}
 
+		restored, err = s.repo.Restore(id, tx)
 		if err != nil {
 			return err
 		}
 
-		if product.Stock < order.Quantity {
-			return domain.ErrInsufficientStock
-		}
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return restored, nil
+}
 
-		product.Stock -= order.Quantity
+// owners may only cancel their own orders, every other transition is admin only
+func (s *OrderService) Transition(id string, transitionDto *dtos.TransitionOrderDTO, sub string, role string, version int) (*domain.Order, error) {
 
-		updatedProduct, err := s.productRepo.Update(product, tx)
-		if updatedProduct == nil {
-			return domain.ErrProductNotFound
+	var updated *domain.Order
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		order, err := s.repo.FindByIDForUpdate(id, false, tx)
+		if order == nil {
+			return domain.ErrOrderNotFound
 		}
 
 		if err != nil {
 			return err
 		}
 
-		restored, err = s.repo.Restore(id, tx)
+		next := enums.Status(transitionDto.Status)
+
+		// only the reservation sweeper lets orders expire
+		if next == enums.Expired {
+			return domain.ErrInvalidTransition
+		}
+
+		// without orders:transition callers may only cancel their own orders
+		if !s.permissions.HasPermission(role, permissionEnums.OrdersTransition) &&
+			(order.UserID != sub || next != enums.Cancelled || !s.permissions.HasPermission(role, permissionEnums.OrdersCancelOwn)) {
+			return domain.ErrNotAllowed
+		}
+
+		if !order.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		updated, err = s.transition(order, next, tx)
 		if err != nil {
 			return err
 		}
@@ -434,8 +467,254 @@ func (s *OrderService) Restore(id string) (bool, error) {
 	})
 
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return restored, nil
+	return updated, nil
+}
+
+// transition moves the order to the next status and restocks on cancel, refund or expiry.
+// Leaving pending ends the reservation, a payment that comes after it ran out is refused
+func (s *OrderService) transition(order *domain.Order, next enums.Status, tx *gorm.DB) (*domain.Order, error) {
+	if !enums.Status(order.Status).CanTransitionTo(next) {
+		return nil, domain.ErrInvalidTransition
+	}
+
+	if next == enums.Paid && order.ReservationExpired(time.Now()) {
+		return nil, domain.ErrReservationExpired
+	}
+
+	if next.ReleasesStock() {
+		items, err := s.repo.FindItemsByOrderID(order.ID, tx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.releaseItems(items, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	order.Status = string(next)
+	order.ReservedUntil = nil
+
+	return s.repo.Update(order, tx)
+}
+
+// ExpireReservations moves pending orders whose reservation ran out to expired and gives their stock back,
+// batch by batch until none are left or ctx is done. Each batch is claimed with SKIP LOCKED in its own
+// transaction, so running it again or on several servers at once never releases an order twice.
+// Every order is expired in its own savepoint: one that fails is logged and left pending, and it is not
+// claimed again in this run so it can't hold back the orders behind it
+func (s *OrderService) ExpireReservations(ctx context.Context, batchSize int) (int, error) {
+	expired := 0
+	var failed []string
+	for ctx.Err() == nil {
+		var claimed, batchExpired int
+		var batchFailed []string
+
+		err := s.repo.GetDatabase(nil).WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			orders, err := s.repo.ClaimExpiredReservations(time.Now(), batchSize, failed, tx)
+			if err != nil {
+				return err
+			}
+			claimed = len(orders)
+
+			for _, order := range orders {
+				err := tx.Transaction(func(orderTx *gorm.DB) error {
+					_, err := s.transition(order, enums.Expired, orderTx)
+					return err
+				})
+				if err != nil {
+					log.Printf("Failed to expire order %s: %v", order.ID, err)
+					batchFailed = append(batchFailed, order.ID)
+					continue
+				}
+				batchExpired++
+			}
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+
+		expired += batchExpired
+		failed = append(failed, batchFailed...)
+		if claimed < batchSize {
+			break
+		}
+	}
+	return expired, nil
+}
+
+// reservationDeadline is when an order placed or edited now stops holding its stock
+func (s *OrderService) reservationDeadline() (time.Time, error) {
+	ttl, err := time.ParseDuration(s.orderConfig.ReservationTTL)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(ttl), nil
+}
+
+// reserveItems locks the products and decreases the stock of the variant every line points at, lines of
+// the same variant are merged. A line may leave the variant out when its product has a single variant.
+// The product name, variant, price and currency are copied onto the line, snapshots in previous win over live values
+func (s *OrderService) reserveItems(orderID string, itemDtos []dtos.OrderItemDTO, previous []domain.OrderItem, tx *gorm.DB) ([]domain.OrderItem, float64, error) {
+	if len(itemDtos) == 0 {
+		return nil, 0, domain.ErrEmptyOrder
+	}
+
+	snapshots := make(map[string]domain.OrderItem, len(previous))
+	for _, item := range previous {
+		snapshots[item.VariantID] = item
+	}
+
+	productIDs := make([]string, 0, len(itemDtos))
+	for _, itemDto := range itemDtos {
+		if itemDto.Quantity <= 0 {
+			return nil, 0, domain.ErrInsufficientStock
+		}
+		if !slices.Contains(productIDs, itemDto.ProductID) {
+			productIDs = append(productIDs, itemDto.ProductID)
+		}
+	}
+
+	// hold the rows so the snapshot and the stock check see the same product
+	lockedProducts, err := s.productRepo.FindByIDsForUpdate(productIDs, false, tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	products := make(map[string]*productDomain.Product, len(lockedProducts))
+	for _, product := range lockedProducts {
+		products[product.ID] = product
+	}
+
+	liveVariants, err := s.productRepo.FindVariants(productIDs, tx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	variants := make(map[string]*productDomain.ProductVariant, len(liveVariants))
+	productVariants := make(map[string][]*productDomain.ProductVariant, len(productIDs))
+	for _, variant := range liveVariants {
+		variants[variant.ID] = variant
+		productVariants[variant.ProductID] = append(productVariants[variant.ProductID], variant)
+	}
+
+	quantities := make(map[string]int, len(itemDtos))
+	variantIDs := make([]string, 0, len(itemDtos))
+	for _, itemDto := range itemDtos {
+		if _, ok := products[itemDto.ProductID]; !ok {
+			return nil, 0, domain.ErrProductNotFound
+		}
+
+		variantID := itemDto.VariantID
+		if variantID == "" {
+			switch len(productVariants[itemDto.ProductID]) {
+			case 0:
+				return nil, 0, domain.ErrVariantNotFound
+			case 1:
+				variantID = productVariants[itemDto.ProductID][0].ID
+			default:
+				return nil, 0, domain.ErrVariantRequired
+			}
+		}
+
+		if variant, ok := variants[variantID]; !ok || variant.ProductID != itemDto.ProductID {
+			return nil, 0, domain.ErrVariantNotFound
+		}
+
+		if _, ok := quantities[variantID]; !ok {
+			variantIDs = append(variantIDs, variantID)
+		}
+		quantities[variantID] += itemDto.Quantity
+	}
+
+	items := make([]domain.OrderItem, 0, len(variantIDs))
+	var total float64
+
+	for _, variantID := range variantIDs {
+		quantity := quantities[variantID]
+		variant := variants[variantID]
+		product := products[variant.ProductID]
+
+		// Decrease variant stock, only succeeds when enough is left
+		reserved, err := s.productRepo.ReserveStock(product.ID, variantID, quantity, orderID, tx)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if !reserved {
+			return nil, 0, domain.ErrInsufficientStock
+		}
+
+		item := domain.OrderItem{
+			ProductID:      product.ID,
+			VariantID:      variantID,
+			Quantity:       quantity,
+			ProductName:    product.Name,
+			SKU:            variant.SKU,
+			VariantOptions: variant.Options,
+			UnitPrice:      variant.UnitPrice(product),
+			Currency:       product.Currency,
+		}
+
+		if snapshot, ok := snapshots[variantID]; ok {
+			item.ProductName = snapshot.ProductName
+			item.SKU = snapshot.SKU
+			item.VariantOptions = snapshot.VariantOptions
+			item.UnitPrice = snapshot.UnitPrice
+			item.Currency = snapshot.Currency
+		}
+
+		if len(items) > 0 && items[0].Currency != item.Currency {
+			return nil, 0, domain.ErrCurrencyMismatch
+		}
+
+		item.Subtotal = float64(quantity) * item.UnitPrice
+		total += item.Subtotal
+
+		items = append(items, item)
+	}
+
+	return items, total, nil
+}
+
+// releaseItems gives the stock of every line back to its variant, deleted products included.
+// Lines whose variant or product was purged have nothing left to give back to and are skipped.
+// The products are locked first and in id order, the same order reserveItems takes them in,
+// so concurrent releases and reservations do not deadlock
+func (s *OrderService) releaseItems(items []domain.OrderItem, tx *gorm.DB) error {
+	sorted := make([]domain.OrderItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ProductID != sorted[j].ProductID {
+			return sorted[i].ProductID < sorted[j].ProductID
+		}
+		return sorted[i].VariantID < sorted[j].VariantID
+	})
+
+	productIDs := make([]string, 0, len(sorted))
+	for _, item := range sorted {
+		if !slices.Contains(productIDs, item.ProductID) {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+	if _, err := s.productRepo.FindByIDsForUpdate(productIDs, true, tx); err != nil {
+		return err
+	}
+
+	for _, item := range sorted {
+		// lines from before variants existed that could not be matched to one
+		if item.VariantID == "" {
+			continue
+		}
+
+		if _, err := s.productRepo.ReleaseStock(item.ProductID, item.VariantID, item.Quantity, item.OrderID, tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,132 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/QuangNV23062004/learning-go/internal/config"
+	"github.com/QuangNV23062004/learning-go/internal/database"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/infrastructure"
+	productDomain "github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	productEnums "github.com/QuangNV23062004/learning-go/internal/pkg/products/enums"
+	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
+	userDomain "github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDatabase connects to the postgres database in TEST_DATABASE_DSN, the test is skipped without one.
+// The database is migrated like the server does on start
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// parallel orders for the last units of a variant must never sell more than the stock
+func TestCreateDoesNotOversell(t *testing.T) {
+	const stock = 5
+	const buyers = 40
+
+	db := openTestDatabase(t)
+	productRepo := productInfrastructure.NewProductRepository(db)
+
+	user := &userDomain.User{Email: uuid.NewString() + "@example.com", Password: "x", Username: "buyer", Birthdate: "2000-01-01"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	product := &productDomain.Product{Name: "limited", Price: 10, Currency: productDomain.DefaultCurrency, UserID: user.ID}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	variant := &productDomain.ProductVariant{ProductID: product.ID, SKU: uuid.NewString(), Options: map[string]string{}}
+	if err := productRepo.CreateVariant(variant, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := productRepo.AdjustStock(product.ID, variant.ID, stock, productEnums.Restock, "test stock", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM audit_events WHERE entity_id IN (SELECT id::text FROM orders WHERE user_id = ?)`, user.ID)
+		db.Exec(`DELETE FROM order_items WHERE product_id = ?`, product.ID)
+		db.Exec(`DELETE FROM orders WHERE user_id = ?`, user.ID)
+		db.Exec(`DELETE FROM inventory_movements WHERE product_id = ?`, product.ID)
+		db.Exec(`DELETE FROM product_variants WHERE product_id = ?`, product.ID)
+		db.Exec(`DELETE FROM products WHERE id = ?`, product.ID)
+		db.Exec(`DELETE FROM users WHERE id = ?`, user.ID)
+	})
+
+	service := NewOrderService(
+		infrastructure.NewOrderRepository(db),
+		userInfrastructure.NewUserRepository(db),
+		productRepo,
+		nil,
+		&config.OrderConfig{ReservationTTL: "30m"},
+	)
+
+	start := make(chan struct{})
+	errs := make(chan error, buyers)
+	var wg sync.WaitGroup
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := service.Create(&dtos.CreateOrderDTO{ProductID: product.ID, Quantity: 1}, user.ID)
+			errs <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	placed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			placed++
+		case !errors.Is(err, domain.ErrInsufficientStock):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if placed != stock {
+		t.Errorf("placed %d orders, want %d", placed, stock)
+	}
+
+	var variantStock, productStock int
+	if err := db.Raw(`SELECT stock FROM product_variants WHERE id = ?`, variant.ID).Scan(&variantStock).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Raw(`SELECT stock FROM products WHERE id = ?`, product.ID).Scan(&productStock).Error; err != nil {
+		t.Fatal(err)
+	}
+	if variantStock != 0 || productStock != 0 {
+		t.Errorf("stock left is %d on the variant and %d on the product, want 0", variantStock, productStock)
+	}
+}
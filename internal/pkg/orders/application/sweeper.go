@@ -0,0 +1,76 @@
+package application
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ReservationSweeper expires unpaid orders in the background so their stock goes back on sale
+type ReservationSweeper struct {
+	service   *OrderService
+	interval  time.Duration
+	batchSize int
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+func NewReservationSweeper(service *OrderService, interval time.Duration, batchSize int) *ReservationSweeper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ReservationSweeper{
+		service:   service,
+		interval:  interval,
+		batchSize: batchSize,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start sweeps once right away, catching up on what ran out while the server was down, then on every tick.
+// Only the first call starts the sweeper, nothing starts after Stop
+func (s *ReservationSweeper) Start() {
+	s.startOnce.Do(s.run)
+}
+
+func (s *ReservationSweeper) run() {
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.sweep()
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the running sweep and waits for it to finish, calling it again is a no-op.
+// A sweeper that was never started has nothing to wait for
+func (s *ReservationSweeper) Stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+		s.startOnce.Do(func() { close(s.done) })
+		<-s.done
+	})
+}
+
+func (s *ReservationSweeper) sweep() {
+	expired, err := s.service.ExpireReservations(s.ctx, s.batchSize)
+	if err != nil && s.ctx.Err() == nil {
+		log.Println("Failed to expire order reservations:", err)
+	}
+	if expired > 0 {
+		log.Printf("Expired %d unpaid orders", expired)
+	}
+}
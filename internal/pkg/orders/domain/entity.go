@@ -1,17 +1,33 @@
 package domain
 
 import (
+	"time"
+
 	"github.com/QuangNV23062004/learning-go/internal/domain"
 )
 
 type Order struct {
 	domain.BaseEntity
-	ProductID string  `json:"product_id" gorm:"type:uuid;not null"`
-	Quantity  int     `json:"quantity" gorm:"not null"`
-	UserID    string  `json:"user_id" gorm:"type:uuid;not null"`
-	Total     float64 `json:"total" gorm:"not null"`
+	UserID   string  `json:"user_id" gorm:"type:uuid;not null"`
+	Total    float64 `json:"total" gorm:"not null"`
+	Currency string  `json:"currency" gorm:"type:char(3);not null;default:'USD'"`
+	Status   string  `json:"status" gorm:"not null;default:'pending';index"`
+
+	// a pending order holds its stock until then, see OrderService.ExpireReservations
+	ReservedUntil *time.Time `json:"reserved_until,omitempty" gorm:"index"`
+
+	Items []OrderItem `json:"items,omitempty" gorm:"foreignKey:OrderID"`
 }
 
 func (o *Order) GetBaseEntity() *domain.BaseEntity {
 	return &o.BaseEntity
 }
+
+func (o *Order) AuditType() string {
+	return "order"
+}
+
+// ReservationExpired reports whether the order stopped holding its stock before now
+func (o *Order) ReservationExpired(now time.Time) bool {
+	return o.ReservedUntil != nil && !o.ReservedUntil.After(now)
+}
@@ -9,4 +9,11 @@ var (
 	ErrNotAllowed         = errors.New("operation not allowed")
 	ErrInsufficientStock  = errors.New("insufficient stock for the product")
 	ErrOldProductNotFound = errors.New("old product not found")
+	ErrEmptyOrder         = errors.New("order must contain at least one item")
+	ErrInvalidTransition  = errors.New("invalid order status transition")
+	ErrOrderNotEditable   = errors.New("order can only be changed while pending")
+	ErrCurrencyMismatch   = errors.New("all order items must use the same currency")
+	ErrVariantNotFound    = errors.New("variant not found for the product")
+	ErrVariantRequired    = errors.New("the product has several variants, variant_id is required")
+	ErrReservationExpired = errors.New("the stock reservation of the order has expired")
 )
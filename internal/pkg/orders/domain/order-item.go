@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+type OrderItem struct {
+	domain.BaseEntity
+	OrderID   string  `json:"order_id" gorm:"type:uuid;not null;index"`
+	ProductID string  `json:"product_id" gorm:"type:uuid;not null;index"`
+	VariantID string  `json:"variant_id" gorm:"type:uuid;index"`
+	Quantity  int     `json:"quantity" gorm:"not null"`
+	Subtotal  float64 `json:"subtotal" gorm:"not null"`
+
+	// snapshot of the product when the line was ordered, later product edits do not change it
+	ProductName string  `json:"product_name" gorm:"not null;default:''"`
+	UnitPrice   float64 `json:"unit_price" gorm:"not null"`
+	Currency    string  `json:"currency" gorm:"type:char(3);not null;default:'USD'"`
+
+	SKU            string            `json:"sku" gorm:"not null;default:''"`
+	VariantOptions map[string]string `json:"variant_options,omitempty" gorm:"type:jsonb;serializer:json"`
+}
+
+func (i *OrderItem) GetBaseEntity() *domain.BaseEntity {
+	return &i.BaseEntity
+}
@@ -1,6 +1,28 @@
 package dtos
 
-type CreateOrderDTO struct {
+type OrderItemDTO struct {
 	ProductID string `json:"product_id" binding:"required,uuid"`
 	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+
+	// may be left out when the product has a single variant
+	VariantID string `json:"variant_id" binding:"omitempty,uuid"`
+}
+
+// ProductID and Quantity are kept so single-product clients keep working,
+// they are treated as a one-item order when Items is empty
+type CreateOrderDTO struct {
+	Items     []OrderItemDTO `json:"items" binding:"omitempty,dive"`
+	ProductID string         `json:"product_id" binding:"omitempty,uuid"`
+	Quantity  int            `json:"quantity" binding:"omitempty,gt=0"`
+	VariantID string         `json:"variant_id" binding:"omitempty,uuid"`
+}
+
+func (d *CreateOrderDTO) GetItems() []OrderItemDTO {
+	if len(d.Items) > 0 {
+		return d.Items
+	}
+	if d.ProductID == "" {
+		return nil
+	}
+	return []OrderItemDTO{{ProductID: d.ProductID, Quantity: d.Quantity, VariantID: d.VariantID}}
 }
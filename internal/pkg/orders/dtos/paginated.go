@@ -3,10 +3,11 @@ package dtos
 type PaginatedProductsQueryDto struct {
 	Page           int    `query:"page" validate:"omitempty,min=1"`
 	Limit          int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor         string `query:"cursor" validate:"omitempty,max=512"`
 	Search         string `query:"search" validate:"omitempty,max=255"`
 	SearchField    string `query:"searchField" validate:"omitempty,oneof=name"`
 	Order          string `query:"order" validate:"omitempty,oneof=asc desc"`
-	SortBy         string `query:"sortBy" validate:"omitempty,oneof=created_at updated_at name"`
+	SortBy         string `query:"sortBy" validate:"omitempty,max=255"`
 	IncludeDeleted bool   `query:"includeDeleted"`
 }
 
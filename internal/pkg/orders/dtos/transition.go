@@ -0,0 +1,5 @@
+package dtos
+
+type TransitionOrderDTO struct {
+	Status string `json:"status" binding:"required,oneof=pending paid shipped delivered cancelled refunded"`
+}
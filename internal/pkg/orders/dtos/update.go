@@ -1,6 +1,18 @@
 package dtos
 
 type UpdateOrderDTO struct {
-	ProductID string `json:"product_id" binding:"required,uuid"`
-	Quantity  int    `json:"quantity" binding:"required,gt=0"`
+	Items     []OrderItemDTO `json:"items" binding:"omitempty,dive"`
+	ProductID string         `json:"product_id" binding:"omitempty,uuid"`
+	Quantity  int            `json:"quantity" binding:"omitempty,gt=0"`
+	VariantID string         `json:"variant_id" binding:"omitempty,uuid"`
+}
+
+func (d *UpdateOrderDTO) GetItems() []OrderItemDTO {
+	if len(d.Items) > 0 {
+		return d.Items
+	}
+	if d.ProductID == "" {
+		return nil
+	}
+	return []OrderItemDTO{{ProductID: d.ProductID, Quantity: d.Quantity, VariantID: d.VariantID}}
 }
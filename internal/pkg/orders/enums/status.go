@@ -0,0 +1,40 @@
+package enums
+
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Paid      Status = "paid"
+	Shipped   Status = "shipped"
+	Delivered Status = "delivered"
+	Cancelled Status = "cancelled"
+	Refunded  Status = "refunded"
+
+	// set by the reservation sweeper when a pending order was not paid in time
+	Expired Status = "expired"
+)
+
+// allowed transitions, cancelled, refunded and expired are terminal
+var transitions = map[Status][]Status{
+	Pending:   {Paid, Cancelled, Expired},
+	Paid:      {Shipped, Cancelled, Refunded},
+	Shipped:   {Delivered, Refunded},
+	Delivered: {Refunded},
+}
+
+// Open are the statuses an order can still leave, each of them may still give its stock back
+var Open = []Status{Pending, Paid, Shipped, Delivered}
+
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// stock goes back to the products only when an order is cancelled, refunded or expired
+func (s Status) ReleasesStock() bool {
+	return s == Cancelled || s == Refunded || s == Expired
+}
@@ -0,0 +1,49 @@
+package enums
+
+import "testing"
+
+func TestCanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		want     bool
+	}{
+		{Pending, Paid, true},
+		{Pending, Cancelled, true},
+		{Pending, Expired, true},
+		{Pending, Shipped, false},
+		{Pending, Refunded, false},
+		{Paid, Shipped, true},
+		{Paid, Refunded, true},
+		{Paid, Expired, false},
+		{Paid, Pending, false},
+		{Shipped, Delivered, true},
+		{Shipped, Cancelled, false},
+		{Delivered, Refunded, true},
+		{Delivered, Shipped, false},
+		{Cancelled, Paid, false},
+		{Refunded, Paid, false},
+		{Expired, Paid, false},
+		{Pending, Pending, false},
+		{Status("unknown"), Paid, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+			t.Errorf("%s -> %s is %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// every status an order can still leave must be listed as open, so purges and reports don't miss it
+func TestOpenStatuses(t *testing.T) {
+	for _, status := range []Status{Pending, Paid, Shipped, Delivered, Cancelled, Refunded, Expired} {
+		open := len(transitions[status]) > 0
+		listed := false
+		for _, s := range Open {
+			listed = listed || s == status
+		}
+		if open != listed {
+			t.Errorf("%s can leave: %v, listed as open: %v", status, open, listed)
+		}
+	}
+}
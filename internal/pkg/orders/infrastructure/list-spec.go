@@ -0,0 +1,38 @@
+package infrastructure
+
+import "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+
+// product and user fields are matched through a subquery so they work whether or not the
+// list joins users, and an order matches when any of its lines matches
+const (
+	orderItemsMatch = "orders.id IN (SELECT order_items.order_id FROM order_items WHERE ?)"
+	orderUserMatch  = "orders.user_id IN (SELECT users.id FROM users WHERE ?)"
+)
+
+var orderListSpec = infrastructure.ListSpec{
+	Sort: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"total":      "total",
+		"status":     "status",
+	},
+	Search: map[string]infrastructure.FilterField{
+		// product columns live on order_items, so product searches go through a subquery
+		"name": {Column: "order_items.product_name", Kind: infrastructure.FilterString, Related: orderItemsMatch},
+	},
+	Filters: infrastructure.FilterFields{
+		"status":         {Column: "status", Kind: infrastructure.FilterString},
+		"currency":       {Column: "currency", Kind: infrastructure.FilterString},
+		"total":          {Column: "total", Kind: infrastructure.FilterNumber},
+		"user_id":        {Column: "user_id", Kind: infrastructure.FilterUUID},
+		"created_at":     {Column: "created_at", Kind: infrastructure.FilterTime},
+		"updated_at":     {Column: "updated_at", Kind: infrastructure.FilterTime},
+		"products.id":    {Column: "order_items.product_id", Kind: infrastructure.FilterUUID, Related: orderItemsMatch},
+		"products.name":  {Column: "order_items.product_name", Kind: infrastructure.FilterString, Related: orderItemsMatch},
+		"products.price": {Column: "order_items.unit_price", Kind: infrastructure.FilterNumber, Related: orderItemsMatch},
+		"variants.id":    {Column: "order_items.variant_id", Kind: infrastructure.FilterUUID, Related: orderItemsMatch},
+		"variants.sku":   {Column: "order_items.sku", Kind: infrastructure.FilterString, Related: orderItemsMatch},
+		"users.email":    {Column: "users.email", Kind: infrastructure.FilterString, Related: orderUserMatch},
+		"users.username": {Column: "users.username", Kind: infrastructure.FilterString, Related: orderUserMatch},
+	},
+}
@@ -1,15 +1,16 @@
 package infrastructure
 
 import (
-	"fmt"
-	"math"
+	"time"
 
 	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/enums"
 	orderType "github.com/QuangNV23062004/learning-go/internal/pkg/orders/types"
 	"github.com/QuangNV23062004/learning-go/internal/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository struct {
@@ -19,7 +20,7 @@ type OrderRepository struct {
 
 func NewOrderRepository(db *gorm.DB) *OrderRepository {
 	return &OrderRepository{
-		BaseRepository: infrastructure.NewBaseRepository[*domain.Order](db),
+		BaseRepository: infrastructure.NewBaseRepository[*domain.Order](db).WithListSpec(orderListSpec),
 		db:             db,
 	}
 }
@@ -40,10 +41,6 @@ func (r *OrderRepository) FindOrdersByUserIDWithOptions(id string, includeDelete
 		selectFields += `, users.username, users.email`
 		where = where.Joins("LEFT JOIN users ON users.id = orders.user_id")
 	}
-	if options.WithProduct {
-		selectFields += `, products.name AS product_name, products.price AS product_price, products.stock AS product_stock`
-		where = where.Joins("LEFT JOIN products ON products.id = orders.product_id")
-	}
 
 	where = where.Select(selectFields)
 
@@ -55,11 +52,20 @@ func (r *OrderRepository) FindOrdersByUserIDWithOptions(id string, includeDelete
 		return nil, err
 	}
 
+	if options.WithProduct {
+		if err := r.attachItems(result, nil); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
-func (r *OrderRepository) FindOrdersByUserIDPaginatedWithOptions(userID string, page int, limit int, search string, searchField string, order string, sortBy string, includeDeleted bool, options types.OrderOptions) (*types.Paginated[*orderType.OrderResponse], error) {
-	var orders []*orderType.OrderResponse
+func (r *OrderRepository) FindOrdersByUserIDPaginatedWithOptions(userID string, page int, limit int, cursor string, search string, searchField string, order string, sortBy string, filters []types.Filter, includeDeleted bool, options types.OrderOptions) (*types.Paginated[*orderType.OrderResponse], error) {
+	sort, err := orderListSpec.ParseSort(sortBy, order)
+	if err != nil {
+		return nil, err
+	}
 
 	where := r.db.Model(&domain.Order{}).Where("orders.user_id = ?", userID)
 
@@ -67,6 +73,17 @@ func (r *OrderRepository) FindOrdersByUserIDPaginatedWithOptions(userID string,
 		where = where.Where("orders.is_deleted = ?", false)
 	}
 
+	if where, err = orderListSpec.ApplySearch(where, search, searchField); err != nil {
+		return nil, err
+	}
+	if where, err = infrastructure.ApplyFilters(where, filters, orderListSpec.Filters); err != nil {
+		return nil, err
+	}
+
+	// count the filtered orders before the user join and the select are added
+	countQuery := where.Session(&gorm.Session{})
+	where = countQuery
+
 	selectFields := `
         orders.*`
 
@@ -74,46 +91,21 @@ func (r *OrderRepository) FindOrdersByUserIDPaginatedWithOptions(userID string,
 		selectFields += `, users.username, users.email`
 		where = where.Joins("LEFT JOIN users ON users.id = orders.user_id")
 	}
-	if options.WithProduct {
-		selectFields += `, products.name AS product_name, products.price AS product_price, products.stock AS product_stock`
-		where = where.Joins("LEFT JOIN products ON products.id = orders.product_id")
-	}
 
 	where = where.Select(selectFields)
 
-	if search != "" && searchField != "" {
-		where = where.Where(searchField+" ILIKE ?", "%"+search+"%")
-	}
-
-	// Count before pagination
-	var total int64
-	countQuery := r.db.Model(&domain.Order{}).Where("user_id = ?", userID)
-	if !includeDeleted {
-		countQuery = countQuery.Where("is_deleted = ?", false)
-	}
-	if err := countQuery.Count(&total).Error; err != nil {
-		return nil, err
-	}
-
-	safeSkip := (page - 1) * limit
-
-	err := where.Order(sortBy + " " + order).Offset(safeSkip).Limit(limit).Find(&orders).Error
+	paginated, err := infrastructure.Paginate[*orderType.OrderResponse](where, countQuery, page, limit, cursor, sort)
 	if err != nil {
 		return nil, err
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	if options.WithProduct {
+		if err := r.attachItems(paginated.Data, nil); err != nil {
+			return nil, err
+		}
+	}
 
-	return &types.Paginated[*orderType.OrderResponse]{
-		Data:        orders,
-		TotalPages:  totalPages,
-		CurrentPage: page,
-		Limit:       limit,
-		Order:       order,
-		SortBy:      sortBy,
-		HasPrevious: page > 1,
-		HasNext:     page < totalPages,
-	}, nil
+	return paginated, nil
 }
 
 func (r *OrderRepository) FindByIDWithOptions(id string, includeDeleted bool, options types.OrderOptions) (*orderType.OrderResponse, error) {
@@ -130,10 +122,6 @@ func (r *OrderRepository) FindByIDWithOptions(id string, includeDeleted bool, op
 		selectFields += `, users.username, users.email`
 		where = where.Joins("LEFT JOIN users ON users.id = orders.user_id")
 	}
-	if options.WithProduct {
-		selectFields += `, products.name AS product_name, products.price AS product_price, products.stock AS product_stock`
-		where = where.Joins("LEFT JOIN products ON products.id = orders.product_id")
-	}
 
 	where = where.Select(selectFields)
 
@@ -142,11 +130,20 @@ func (r *OrderRepository) FindByIDWithOptions(id string, includeDeleted bool, op
 		return nil, err
 	}
 
+	if options.WithProduct {
+		if err := r.attachItems([]*orderType.OrderResponse{order}, nil); err != nil {
+			return nil, err
+		}
+	}
+
 	return order, nil
 }
 
-func (r *OrderRepository) PaginatedWithOptions(page int, limit int, search string, searchField string, order string, sortBy string, includeDeleted bool, options types.OrderOptions) (*types.Paginated[*orderType.OrderResponse], error) {
-	var orders []*orderType.OrderResponse
+func (r *OrderRepository) PaginatedWithOptions(page int, limit int, cursor string, search string, searchField string, order string, sortBy string, filters []types.Filter, includeDeleted bool, options types.OrderOptions) (*types.Paginated[*orderType.OrderResponse], error) {
+	sort, err := orderListSpec.ParseSort(sortBy, order)
+	if err != nil {
+		return nil, err
+	}
 
 	where := r.db.Model(new(domain.Order))
 
@@ -154,6 +151,17 @@ func (r *OrderRepository) PaginatedWithOptions(page int, limit int, search strin
 		where = where.Where("orders.is_deleted = ?", false)
 	}
 
+	if where, err = orderListSpec.ApplySearch(where, search, searchField); err != nil {
+		return nil, err
+	}
+	if where, err = infrastructure.ApplyFilters(where, filters, orderListSpec.Filters); err != nil {
+		return nil, err
+	}
+
+	// count the filtered orders before the user join and the select are added
+	countQuery := where.Session(&gorm.Session{})
+	where = countQuery
+
 	selectFields := `
         orders.*`
 
@@ -161,45 +169,21 @@ func (r *OrderRepository) PaginatedWithOptions(page int, limit int, search strin
 		selectFields += `, users.username, users.email`
 		where = where.Joins("LEFT JOIN users ON users.id = orders.user_id")
 	}
-	if options.WithProduct {
-		selectFields += `, products.name AS product_name, products.price AS product_price, products.stock AS product_stock`
-		where = where.Joins("LEFT JOIN products ON products.id = orders.product_id")
-	}
 
 	where = where.Select(selectFields)
 
-	if search != "" && searchField != "" {
-		where = where.Where(fmt.Sprintf("%s ILIKE ?", searchField), "%"+search+"%")
-	}
-
-	// Count before pagination
-	var total int64
-	countQuery := r.db.Model(new(domain.Order))
-	if !includeDeleted {
-		countQuery = countQuery.Where("is_deleted = ?", false)
-	}
-	if err := countQuery.Count(&total).Error; err != nil {
+	paginated, err := infrastructure.Paginate[*orderType.OrderResponse](where, countQuery, page, limit, cursor, sort)
+	if err != nil {
 		return nil, err
 	}
 
-	skip := (page - 1) * limit
-
-	if err := where.Order(sortBy + " " + order).Offset(skip).Limit(limit).Find(&orders).Error; err != nil {
-		return nil, err
+	if options.WithProduct {
+		if err := r.attachItems(paginated.Data, nil); err != nil {
+			return nil, err
+		}
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
-
-	return &types.Paginated[*orderType.OrderResponse]{
-		Data:        orders,
-		TotalPages:  totalPages,
-		CurrentPage: page,
-		Limit:       limit,
-		Order:       order,
-		SortBy:      sortBy,
-		HasPrevious: page > 1,
-		HasNext:     page < totalPages,
-	}, nil
+	return paginated, nil
 }
 
 func (r *OrderRepository) FindAllWithOptions(includeDeleted bool, options types.OrderOptions) ([]*orderType.OrderResponse, error) {
@@ -216,15 +200,147 @@ func (r *OrderRepository) FindAllWithOptions(includeDeleted bool, options types.
 		selectFields += `, users.username, users.email`
 		where = where.Joins("LEFT JOIN users ON users.id = orders.user_id")
 	}
-	if options.WithProduct {
-		selectFields += `, products.name AS product_name, products.price AS product_price, products.stock AS product_stock`
-		where = where.Joins("LEFT JOIN products ON products.id = orders.product_id")
-	}
 
 	where = where.Select(selectFields)
 
 	if err := where.Find(&orders).Error; err != nil {
 		return nil, err
 	}
+
+	if options.WithProduct {
+		if err := r.attachItems(orders, nil); err != nil {
+			return nil, err
+		}
+	}
 	return orders, nil
 }
+
+// FindByIDForUpdate locks the order row until the transaction ends. Orders are locked before the products
+// of their lines so writers and the reservation sweeper take rows in the same order
+func (r *OrderRepository) FindByIDForUpdate(id string, includeDeleted bool, tx *gorm.DB) (*domain.Order, error) {
+	var order domain.Order
+
+	where := r.GetDatabase(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id)
+	if !includeDeleted {
+		where = where.Where("is_deleted = ?", false)
+	}
+
+	if err := where.First(&order).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ClaimExpiredReservations locks up to limit pending orders whose reservation ran out before now, leaving out
+// exceptIDs. Rows another transaction already holds are skipped, so several sweepers never work on the same order
+func (r *OrderRepository) ClaimExpiredReservations(now time.Time, limit int, exceptIDs []string, tx *gorm.DB) ([]*domain.Order, error) {
+	var orders []*domain.Order
+	where := r.GetDatabase(tx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND reserved_until <= ?", enums.Pending, now)
+	if len(exceptIDs) > 0 {
+		where = where.Where("id NOT IN ?", exceptIDs)
+	}
+	err := where.
+		Order("reserved_until asc").
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// FindItemsByOrderID returns the lines of the order in the order they were added
+func (r *OrderRepository) FindItemsByOrderID(orderID string, tx *gorm.DB) ([]domain.OrderItem, error) {
+	var items []domain.OrderItem
+	if err := r.GetDatabase(tx).Where("order_id = ?", orderID).Order("created_at asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *OrderRepository) DeleteItemsByOrderID(orderID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("order_id = ?", orderID).Delete(&domain.OrderItem{}).Error
+}
+
+func (r *OrderRepository) CreateItems(items []domain.OrderItem, tx *gorm.DB) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.GetDatabase(tx).Create(&items).Error
+}
+
+// attachItems loads the line items of every order in one query, product data comes from the snapshot on each line
+func (r *OrderRepository) attachItems(orders []*orderType.OrderResponse, tx *gorm.DB) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(orders))
+	byID := make(map[string]*orderType.OrderResponse, len(orders))
+	for _, order := range orders {
+		if order == nil {
+			continue
+		}
+		order.LineItems = []*domain.OrderItem{}
+		ids = append(ids, order.ID)
+		byID[order.ID] = order
+	}
+
+	var items []*domain.OrderItem
+	if err := r.GetDatabase(tx).Where("order_id IN ?", ids).Order("created_at asc").Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if order, ok := byID[item.OrderID]; ok {
+			order.LineItems = append(order.LineItems, item)
+		}
+	}
+
+	return nil
+}
+
+// FindItemsByUserIDAndStatus returns the lines of the user's orders that are in one of the statuses, deleted orders included
+func (r *OrderRepository) FindItemsByUserIDAndStatus(userID string, statuses []string, tx *gorm.DB) ([]domain.OrderItem, error) {
+	var items []domain.OrderItem
+	err := r.GetDatabase(tx).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status IN ?", userID, statuses).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// HasOrdersOnSellerProducts reports whether somebody other than the seller has an order in one of the statuses
+// with a line of the seller's products, deleted orders included
+func (r *OrderRepository) HasOrdersOnSellerProducts(sellerID string, statuses []string, tx *gorm.DB) (bool, error) {
+	var count int64
+	err := r.GetDatabase(tx).Model(&domain.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id <> ? AND orders.status IN ?", sellerID, statuses).
+		Where("order_items.product_id IN (SELECT id FROM products WHERE user_id = ?)", sellerID).
+		Limit(1).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// PurgeByUserID permanently removes every order of the user with its lines and returns how many orders went
+func (r *OrderRepository) PurgeByUserID(userID string, tx *gorm.DB) (int64, error) {
+	db := r.GetDatabase(tx)
+
+	err := db.Where("order_id IN (?)", db.Model(&domain.Order{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&domain.OrderItem{}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	result := db.Where("user_id = ?", userID).Delete(&domain.Order{})
+	return result.RowsAffected, result.Error
+}
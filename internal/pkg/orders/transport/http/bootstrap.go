@@ -1,9 +1,15 @@
 package http
 
 import (
+	"log"
+	"time"
+
 	"github.com/QuangNV23062004/learning-go/internal/config"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/application"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/orders/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
 	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
 	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/utils"
@@ -12,14 +18,24 @@ import (
 	"gorm.io/gorm"
 )
 
-func BootstrapOrderRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig) {
+func BootstrapOrderRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig, orderConfig *config.OrderConfig) *application.ReservationSweeper {
 
 	jwtService := utils.NewJwtService(jwtConfig)
 	repo := infrastructure.NewOrderRepository(db)
 	userRepository := userInfrastructure.NewUserRepository(db)
 	productRepository := productInfrastructure.NewProductRepository(db)
-	orderService := application.NewOrderService(repo, userRepository, productRepository)
+	permissionService := permissionApplication.NewPermissionService(permissionInfrastructure.NewPermissionRepository(db), auditInfrastructure.NewAuditRepository(db))
+	orderService := application.NewOrderService(repo, userRepository, productRepository, permissionService, orderConfig)
 	orderHandler := NewOrderHandler(orderService)
-	orderRouter := NewRouter(orderHandler, jwtService)
+	orderRouter := NewRouter(orderHandler, jwtService, userRepository, permissionService)
 	orderRouter.SetupRoutes(api)
+
+	if _, err := time.ParseDuration(orderConfig.ReservationTTL); err != nil {
+		log.Fatal("Invalid order reservation ttl:", err)
+	}
+	sweepInterval, err := time.ParseDuration(orderConfig.SweepInterval)
+	if err != nil || sweepInterval <= 0 {
+		log.Fatal("Invalid order sweep interval:", orderConfig.SweepInterval)
+	}
+	return application.NewReservationSweeper(orderService, sweepInterval, max(1, orderConfig.SweepBatchSize))
 }
@@ -21,8 +21,11 @@ func NewOrderHandler(service *application.OrderService) *OrderHandler {
 
 func (h *OrderHandler) FindOrderByID(c fiber.Ctx) error {
 
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
 	id := c.Params("id")
 	includeDeleted := c.Query("includeDeleted") == "true"
 
@@ -36,8 +39,11 @@ func (h *OrderHandler) FindOrderByID(c fiber.Ctx) error {
 
 func (h *OrderHandler) FindOrdersByUserID(c fiber.Ctx) error {
 
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
 	userID := c.Params("id")
 	includeDeleted := c.Query("includeDeleted") == "true"
 	orders, err := h.service.FindOrdersByUserID(userID, includeDeleted, sub, role)
@@ -51,8 +57,11 @@ func (h *OrderHandler) FindPaginatedOrdersByUserID(c fiber.Ctx) error {
 
 	var query dtos.PaginatedProductsQueryDto
 
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
 	userID := c.Params("id")
 
 	if err := c.Bind().Query(&query); err != nil {
@@ -63,7 +72,12 @@ func (h *OrderHandler) FindPaginatedOrdersByUserID(c fiber.Ctx) error {
 	// Apply defaults
 	query.ApplyDefaults()
 
-	orders, err := h.service.FindPaginatedOrdersByUserID(userID, query.Page, query.Limit, query.Search, query.SearchField, query.Order, query.SortBy, query.IncludeDeleted, sub, role)
+	filters, err := http.ParseFilters(c)
+	if err != nil {
+		return err
+	}
+
+	orders, err := h.service.FindPaginatedOrdersByUserID(userID, query.Page, query.Limit, query.Cursor, query.Search, query.SearchField, query.Order, query.SortBy, filters, query.IncludeDeleted, sub, role)
 
 	if err != nil {
 		return err
@@ -73,7 +87,7 @@ func (h *OrderHandler) FindPaginatedOrdersByUserID(c fiber.Ctx) error {
 }
 
 func (h *OrderHandler) FindAllOrders(c fiber.Ctx) error {
-	role := c.Locals("role").(string)
+	role := http.Role(c)
 	includeDeleted := c.Query("includeDeleted") == "true"
 
 	orders, err := h.service.FindAllOrders(includeDeleted, role)
@@ -87,7 +101,7 @@ func (h *OrderHandler) FindAllOrders(c fiber.Ctx) error {
 func (h *OrderHandler) PaginatedOrders(c fiber.Ctx) error {
 
 	var query dtos.PaginatedProductsQueryDto
-	role := c.Locals("role").(string)
+	role := http.Role(c)
 	if err := c.Bind().Query(&query); err != nil {
 		err := http.ErrInvalidQuery
 		return err
@@ -96,7 +110,12 @@ func (h *OrderHandler) PaginatedOrders(c fiber.Ctx) error {
 	// Apply defaults
 	query.ApplyDefaults()
 
-	orders, err := h.service.Paginated(query.Page, query.Limit, query.Search, query.SearchField, query.Order, query.SortBy, query.IncludeDeleted, role)
+	filters, err := http.ParseFilters(c)
+	if err != nil {
+		return err
+	}
+
+	orders, err := h.service.Paginated(query.Page, query.Limit, query.Cursor, query.Search, query.SearchField, query.Order, query.SortBy, filters, query.IncludeDeleted, role)
 	if err != nil {
 		return err
 	}
@@ -106,7 +125,10 @@ func (h *OrderHandler) PaginatedOrders(c fiber.Ctx) error {
 
 func (h *OrderHandler) CreateOrder(c fiber.Ctx) error {
 	var orderDto dtos.CreateOrderDTO
-	sub := c.Locals("sub").(string)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
 	if err := c.Bind().Body(&orderDto); err != nil {
 		return http.ErrInvalidBody
 	}
@@ -122,14 +144,22 @@ func (h *OrderHandler) CreateOrder(c fiber.Ctx) error {
 func (h *OrderHandler) UpdateOrder(c fiber.Ctx) error {
 	var orderDto dtos.UpdateOrderDTO
 	id := c.Params("id")
-	sub := c.Locals("sub").(string)
-	role := c.Locals("role").(string)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	role := http.Role(c)
 
 	if err := c.Bind().Body(&orderDto); err != nil {
 		return http.ErrInvalidBody
 	}
 
-	updatedOrder, err := h.service.Update(id, &orderDto, sub, role)
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	updatedOrder, err := h.service.Update(id, &orderDto, sub, role, version)
 	if err != nil {
 		return err
 	}
@@ -140,13 +170,47 @@ func (h *OrderHandler) UpdateOrder(c fiber.Ctx) error {
 
 func (h *OrderHandler) DeleteOrder(c fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
 
-	deleted, err := h.service.Delete(id, sub, role)
+	deleted, err := h.service.Delete(id, sub, role, version)
 	if err != nil {
 		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(utils.Success(deleted, fiber.StatusOK))
 }
+
+func (h *OrderHandler) TransitionOrder(c fiber.Ctx) error {
+	var transitionDto dtos.TransitionOrderDTO
+	id := c.Params("id")
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	role := http.Role(c)
+
+	if err := c.Bind().Body(&transitionDto); err != nil {
+		return http.ErrInvalidBody
+	}
+
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	updatedOrder, err := h.service.Transition(id, &transitionDto, sub, role, version)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(updatedOrder, fiber.StatusOK))
+}
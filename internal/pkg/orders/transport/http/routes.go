@@ -2,56 +2,64 @@ package http
 
 import (
 	"github.com/QuangNV23062004/learning-go/internal/middlewares"
-	"github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
 	"github.com/QuangNV23062004/learning-go/internal/utils"
 
 	"github.com/gofiber/fiber/v3"
 )
 
 type Router struct {
-	handler    *OrderHandler
-	jwtService *utils.JwtService
+	handler       *OrderHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
 }
 
-func NewRouter(handler *OrderHandler, jwtService *utils.JwtService) *Router {
+func NewRouter(handler *OrderHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
 	return &Router{
-		handler:    handler,
-		jwtService: jwtService,
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
 	}
 }
 
 func (r *Router) SetupRoutes(appGroup fiber.Router) {
 	ordersGroup := appGroup.Group("/orders")
 	ordersGroup.Use(
-		middlewares.AuthMiddleware(r.jwtService),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
 	)
 
 	ordersGroup.Get("/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersReadOwn), string(enums.OrdersReadAny)),
 		r.handler.FindOrderByID)
 
 	ordersGroup.Get("/user/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersReadOwn), string(enums.OrdersReadAny)),
 		r.handler.FindOrdersByUserID)
 
 	ordersGroup.Get("/user/:id/paginated",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersReadOwn), string(enums.OrdersReadAny)),
 		r.handler.FindPaginatedOrdersByUserID)
 
 	ordersGroup.Post("/",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersCreate)),
 		r.handler.CreateOrder)
 
 	ordersGroup.Patch("/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersUpdateOwn), string(enums.OrdersUpdateAny)),
 		r.handler.UpdateOrder)
 
+	ordersGroup.Post("/:id/transitions",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersCancelOwn), string(enums.OrdersTransition)),
+		r.handler.TransitionOrder)
+
 	ordersGroup.Delete("/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersDeleteOwn), string(enums.OrdersDeleteAny)),
 		r.handler.DeleteOrder)
 
 	ordersGroup.Use(
-		middlewares.RoleMiddleware([]string{string(enums.Admin)}))
+		middlewares.PermissionMiddleware(r.permissions, string(enums.OrdersReadAny)))
 
 	ordersGroup.Get("/", r.handler.PaginatedOrders)
 	ordersGroup.Get("/all", r.handler.FindAllOrders)
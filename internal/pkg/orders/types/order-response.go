@@ -12,7 +12,6 @@ type OrderResponse struct {
 	Username string `json:"username,omitempty" gorm:"column:username"`
 	Email    string `json:"email,omitempty" gorm:"column:email"`
 
-	// Enriched from Product
-	ProductName  string  `json:"product_name,omitempty" gorm:"column:product_name"`
-	ProductPrice float64 `json:"product_price,omitempty" gorm:"column:product_price"`
+	// Line items with their product snapshot, loaded separately from order_items
+	LineItems []*domain.OrderItem `json:"items" gorm:"-"`
 }
@@ -0,0 +1,113 @@
+package application
+
+import (
+	"log"
+
+	"github.com/QuangNV23062004/learning-go/internal/audit"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
+	roleEnums "github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
+
+	"gorm.io/gorm"
+)
+
+type PermissionService struct {
+	repo      *infrastructure.PermissionRepository
+	auditRepo *auditInfrastructure.AuditRepository
+}
+
+func NewPermissionService(repo *infrastructure.PermissionRepository, auditRepo *auditInfrastructure.AuditRepository) *PermissionService {
+	return &PermissionService{
+		repo:      repo,
+		auditRepo: auditRepo,
+	}
+}
+
+// HasAnyPermission is what PermissionMiddleware asks, the role comes from the access token
+func (s *PermissionService) HasAnyPermission(role string, permissions ...string) (bool, error) {
+	if role == "" || len(permissions) == 0 {
+		return false, nil
+	}
+	return s.repo.HasAny(role, permissions, nil)
+}
+
+// HasPermission is the service layer check, a failed lookup denies
+func (s *PermissionService) HasPermission(role string, permission enums.Permission) bool {
+	allowed, err := s.HasAnyPermission(role, string(permission))
+	if err != nil {
+		log.Println("Permission lookup failed:", err)
+		return false
+	}
+	return allowed
+}
+
+func (s *PermissionService) ListPermissions() ([]domain.Permission, error) {
+	return s.repo.FindAllPermissions(nil)
+}
+
+func (s *PermissionService) GetRolePermissions(role string) ([]string, error) {
+	if !isKnownRole(role) {
+		return nil, domain.ErrUnknownRole
+	}
+	return s.repo.FindByRole(role, nil)
+}
+
+func (s *PermissionService) UpdateRolePermissions(role string, dto *dtos.UpdateRolePermissionsDTO, sub string) ([]string, error) {
+	if !isKnownRole(role) {
+		return nil, domain.ErrUnknownRole
+	}
+
+	seen := map[string]bool{}
+	permissions := make([]string, 0, len(dto.Permissions))
+	for _, permission := range dto.Permissions {
+		if !enums.IsKnown(permission) {
+			return nil, domain.ErrUnknownPermission
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			permissions = append(permissions, permission)
+		}
+	}
+
+	// otherwise nobody could ever edit permissions again
+	if role == string(roleEnums.Admin) && !seen[string(enums.PermissionsManage)] {
+		return nil, domain.ErrPermissionLockout
+	}
+
+	var updated []string
+
+	db := s.repo.GetDatabase(nil)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		previous, err := s.repo.FindByRole(role, tx)
+		if err != nil {
+			return err
+		}
+
+		if err := s.repo.ReplaceForRole(role, permissions, tx); err != nil {
+			return err
+		}
+
+		updated, err = s.repo.FindByRole(role, tx)
+		if err != nil {
+			return err
+		}
+
+		return s.auditRepo.Record(sub, audit.RolePermissionsUpdated, "role", role, map[string][]string{
+			"from": previous,
+			"to":   updated,
+		}, tx)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func isKnownRole(role string) bool {
+	return role == string(roleEnums.Admin) || role == string(roleEnums.User)
+}
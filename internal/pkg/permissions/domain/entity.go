@@ -0,0 +1,24 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// Permission is a known permission name, the rows tell which ones were already seeded
+type Permission struct {
+	Name        string `json:"name" gorm:"not null;uniqueIndex"`
+	Description string `json:"description" gorm:"not null;default:''"`
+	domain.BaseEntity
+}
+
+func (p *Permission) GetBaseEntity() *domain.BaseEntity {
+	return &p.BaseEntity
+}
+
+type RolePermission struct {
+	Role       string `json:"role" gorm:"not null;uniqueIndex:idx_role_permission"`
+	Permission string `json:"permission" gorm:"not null;uniqueIndex:idx_role_permission"`
+	domain.BaseEntity
+}
+
+func (r *RolePermission) GetBaseEntity() *domain.BaseEntity {
+	return &r.BaseEntity
+}
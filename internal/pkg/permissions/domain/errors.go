@@ -0,0 +1,9 @@
+package domain
+
+import "errors"
+
+var (
+	ErrUnknownRole       = errors.New("unknown role")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrPermissionLockout = errors.New("the admin role must keep permissions:manage")
+)
@@ -0,0 +1,5 @@
+package dtos
+
+type UpdateRolePermissionsDTO struct {
+	Permissions []string `json:"permissions" validate:"required"`
+}
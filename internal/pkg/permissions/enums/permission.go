@@ -0,0 +1,131 @@
+package enums
+
+import roleEnums "github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
+
+type Permission string
+
+// ":own" permissions cover the caller's own records, ":any" ones everybody's
+const (
+	UsersList        Permission = "users:list"
+	UsersRead        Permission = "users:read"
+	UsersReadDeleted Permission = "users:read:deleted"
+	UsersUpdateOwn   Permission = "users:update:own"
+	UsersDeleteOwn   Permission = "users:delete:own"
+	UsersDeleteAny   Permission = "users:delete:any"
+	UsersRestore     Permission = "users:restore"
+	UsersUnlock      Permission = "users:unlock"
+	UsersChangeRole  Permission = "users:role"
+	UsersSuspend     Permission = "users:suspend"
+	UsersPurge       Permission = "users:purge"
+
+	ProductsCreate      Permission = "products:create"
+	ProductsReadDeleted Permission = "products:read:deleted"
+	ProductsUpdateOwn   Permission = "products:update:own"
+	ProductsUpdateAny   Permission = "products:update:any"
+	ProductsDeleteOwn   Permission = "products:delete:own"
+	ProductsDeleteAny   Permission = "products:delete:any"
+	ProductsRestore     Permission = "products:restore"
+
+	CategoriesManage Permission = "categories:manage"
+
+	InventoryRead   Permission = "inventory:read"
+	InventoryAdjust Permission = "inventory:adjust"
+
+	CartManage Permission = "cart:manage"
+
+	OrdersCreate      Permission = "orders:create"
+	OrdersReadOwn     Permission = "orders:read:own"
+	OrdersReadAny     Permission = "orders:read:any"
+	OrdersReadDeleted Permission = "orders:read:deleted"
+	OrdersUpdateOwn   Permission = "orders:update:own"
+	OrdersUpdateAny   Permission = "orders:update:any"
+	OrdersCancelOwn   Permission = "orders:cancel:own"
+	OrdersTransition  Permission = "orders:transition"
+	OrdersDeleteOwn   Permission = "orders:delete:own"
+	OrdersDeleteAny   Permission = "orders:delete:any"
+
+	PermissionsManage Permission = "permissions:manage"
+
+	AuditRead Permission = "audit:read"
+)
+
+// All lists every permission the code checks, with a description for the admin ui
+var All = []struct {
+	Name        Permission
+	Description string
+}{
+	{UsersList, "List all users"},
+	{UsersRead, "View user profiles"},
+	{UsersReadDeleted, "View deleted users"},
+	{UsersUpdateOwn, "Update own profile, password, email and 2fa"},
+	{UsersDeleteOwn, "Delete own account"},
+	{UsersDeleteAny, "Delete any user"},
+	{UsersRestore, "Restore deleted users"},
+	{UsersUnlock, "Unlock accounts locked after failed logins"},
+	{UsersChangeRole, "Change the role of users"},
+	{UsersSuspend, "Suspend and reactivate accounts"},
+	{UsersPurge, "Permanently delete users with their products and orders"},
+
+	{ProductsCreate, "Create products"},
+	{ProductsReadDeleted, "View deleted products"},
+	{ProductsUpdateOwn, "Update own products"},
+	{ProductsUpdateAny, "Update any product"},
+	{ProductsDeleteOwn, "Delete own products"},
+	{ProductsDeleteAny, "Delete any product"},
+	{ProductsRestore, "Restore deleted products"},
+
+	{CategoriesManage, "Create, edit and delete product categories"},
+
+	{InventoryRead, "View the stock history of products"},
+	{InventoryAdjust, "Post manual stock adjustments and restocks"},
+
+	{CartManage, "Keep a shopping cart"},
+
+	{OrdersCreate, "Place orders"},
+	{OrdersReadOwn, "View own orders"},
+	{OrdersReadAny, "View every order"},
+	{OrdersReadDeleted, "View deleted orders"},
+	{OrdersUpdateOwn, "Edit own pending orders"},
+	{OrdersUpdateAny, "Edit any pending order"},
+	{OrdersCancelOwn, "Cancel own orders"},
+	{OrdersTransition, "Move any order to any allowed status"},
+	{OrdersDeleteOwn, "Delete own orders"},
+	{OrdersDeleteAny, "Delete any order"},
+
+	{PermissionsManage, "Edit role permissions"},
+
+	{AuditRead, "Read the audit log"},
+}
+
+// Defaults are granted when a permission is first seeded, later edits by admins are kept
+var Defaults = map[roleEnums.Role][]Permission{
+	roleEnums.User: {
+		UsersRead, UsersUpdateOwn, UsersDeleteOwn,
+		ProductsCreate, ProductsUpdateOwn, ProductsDeleteOwn,
+		CartManage,
+		OrdersCreate, OrdersReadOwn, OrdersUpdateOwn, OrdersCancelOwn, OrdersDeleteOwn,
+	},
+}
+
+func IsKnown(name string) bool {
+	for _, permission := range All {
+		if string(permission.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRoles returns the roles that get the permission when it is first seeded, admin gets everything
+func DefaultRoles(permission Permission) []string {
+	roles := []string{string(roleEnums.Admin)}
+	for role, permissions := range Defaults {
+		for _, p := range permissions {
+			if p == permission {
+				roles = append(roles, string(role))
+				break
+			}
+		}
+	}
+	return roles
+}
@@ -0,0 +1,70 @@
+package infrastructure
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/domain"
+
+	"gorm.io/gorm"
+)
+
+type PermissionRepository struct {
+	*infrastructure.BaseRepository[*domain.RolePermission]
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) *PermissionRepository {
+	return &PermissionRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.RolePermission](db),
+		db:             db,
+	}
+}
+
+// HasAny reports whether the role holds at least one of the permissions
+func (r *PermissionRepository) HasAny(role string, permissions []string, tx *gorm.DB) (bool, error) {
+	var count int64
+	err := r.GetDatabase(tx).Model(&domain.RolePermission{}).
+		Where("role = ? AND permission IN ?", role, permissions).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *PermissionRepository) FindAllPermissions(tx *gorm.DB) ([]domain.Permission, error) {
+	var permissions []domain.Permission
+	if err := r.GetDatabase(tx).Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *PermissionRepository) FindByRole(role string, tx *gorm.DB) ([]string, error) {
+	permissions := []string{}
+	err := r.GetDatabase(tx).Model(&domain.RolePermission{}).
+		Where("role = ?", role).
+		Order("permission").
+		Pluck("permission", &permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ReplaceForRole sets the role's permissions to exactly the given ones
+func (r *PermissionRepository) ReplaceForRole(role string, permissions []string, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	if err := db.Where("role = ?", role).Delete(&domain.RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	rows := make([]domain.RolePermission, 0, len(permissions))
+	for _, permission := range permissions {
+		rows = append(rows, domain.RolePermission{Role: role, Permission: permission})
+	}
+	return db.Create(&rows).Error
+}
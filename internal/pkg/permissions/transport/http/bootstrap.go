@@ -0,0 +1,24 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/config"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
+	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+	"gorm.io/gorm"
+)
+
+func BootstrapPermissionRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig) {
+
+	jwtService := utils.NewJwtService(jwtConfig)
+	permissionRepository := infrastructure.NewPermissionRepository(db)
+	userRepository := userInfrastructure.NewUserRepository(db)
+	permissionService := application.NewPermissionService(permissionRepository, auditInfrastructure.NewAuditRepository(db))
+	permissionHandler := NewPermissionHandler(permissionService)
+	permissionRouter := NewRouter(permissionHandler, jwtService, userRepository, permissionService)
+	permissionRouter.SetupRoutes(api)
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	httpError "github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type PermissionHandler struct {
+	service *application.PermissionService
+}
+
+func NewPermissionHandler(service *application.PermissionService) *PermissionHandler {
+	return &PermissionHandler{
+		service: service,
+	}
+}
+
+func (h *PermissionHandler) ListPermissions(c fiber.Ctx) error {
+	permissions, err := h.service.ListPermissions()
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		permissions, fiber.StatusOK,
+	))
+}
+
+func (h *PermissionHandler) GetRolePermissions(c fiber.Ctx) error {
+	role := c.Params("role")
+	permissions, err := h.service.GetRolePermissions(role)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		permissions, fiber.StatusOK,
+	))
+}
+
+func (h *PermissionHandler) UpdateRolePermissions(c fiber.Ctx) error {
+	role := c.Params("role")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.UpdateRolePermissionsDTO
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	permissions, err := h.service.UpdateRolePermissions(role, &body, sub)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		permissions, fiber.StatusOK,
+	))
+}
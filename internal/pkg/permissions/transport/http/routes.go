@@ -0,0 +1,40 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/middlewares"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type Router struct {
+	handler       *PermissionHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
+}
+
+func NewRouter(handler *PermissionHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
+	return &Router{
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
+	}
+}
+
+func (r *Router) SetupRoutes(app fiber.Router) {
+	permission := app.Group("/permissions")
+
+	permission.Use(
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.PermissionsManage)),
+	)
+
+	permission.Get("/", r.handler.ListPermissions)
+
+	permission.Get("/roles/:role", r.handler.GetRolePermissions)
+
+	permission.Put("/roles/:role", r.handler.UpdateRolePermissions)
+}
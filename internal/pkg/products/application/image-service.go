@@ -0,0 +1,215 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+
+	"github.com/QuangNV23062004/learning-go/internal/http"
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionEnums "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/storage"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ProductImageService struct {
+	repo          *infrastructure.ProductImageRepository
+	productRepo   *infrastructure.ProductRepository
+	storage       storage.Storage
+	permissions   *permissionApplication.PermissionService
+	maxSize       int
+	thumbnailSize int
+}
+
+func NewProductImageService(repo *infrastructure.ProductImageRepository, productRepo *infrastructure.ProductRepository, fileStorage storage.Storage, permissions *permissionApplication.PermissionService, maxSize int, thumbnailSize int) *ProductImageService {
+	return &ProductImageService{
+		repo:          repo,
+		productRepo:   productRepo,
+		storage:       fileStorage,
+		permissions:   permissions,
+		maxSize:       maxSize,
+		thumbnailSize: thumbnailSize,
+	}
+}
+
+func (s *ProductImageService) MaxSize() int {
+	return s.maxSize
+}
+
+func (s *ProductImageService) ListImages(productID string) ([]*domain.ProductImage, error) {
+	product, err := s.productRepo.FindByID(productID, false, nil)
+	if product == nil || err != nil {
+		return nil, domain.ErrProductNotFound
+	}
+
+	images, err := s.repo.FindByProductID(productID, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.withURLs(images...)
+	return images, nil
+}
+
+// UploadImage checks the bytes are really an image, stores it with a thumbnail and appends it to the product's images
+func (s *ProductImageService) UploadImage(productID string, data []byte, sub string, role string) (*domain.ProductImage, error) {
+	if err := s.ensureCanEdit(productID, sub, role); err != nil {
+		return nil, err
+	}
+
+	if len(data) > s.maxSize {
+		return nil, domain.ErrImageTooLarge
+	}
+
+	contentType, err := utils.SniffImage(data)
+	if err != nil {
+		return nil, domain.ErrUnsupportedImage
+	}
+
+	thumbnail, width, height, err := utils.MakeThumbnail(data, s.thumbnailSize)
+	if err != nil {
+		return nil, domain.ErrUnsupportedImage
+	}
+
+	name := uuid.NewString()
+	image := &domain.ProductImage{
+		ProductID:    productID,
+		Key:          "products/" + productID + "/" + name + "." + utils.ImageExtensions[contentType],
+		ThumbnailKey: "products/" + productID + "/" + name + "_thumb.jpg",
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        width,
+		Height:       height,
+	}
+
+	ctx := context.Background()
+	if err := s.storage.Put(ctx, image.Key, data, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(ctx, image.ThumbnailKey, thumbnail, "image/jpeg"); err != nil {
+		s.removeFiles(image)
+		return nil, err
+	}
+
+	var created *domain.ProductImage
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// the product row lock keeps two uploads from taking the same position
+		products, err := s.productRepo.FindByIDsForUpdate([]string{productID}, false, tx)
+		if err != nil {
+			return err
+		}
+		if len(products) == 0 {
+			return domain.ErrProductNotFound
+		}
+
+		image.Position, err = s.repo.NextPosition(productID, tx)
+		if err != nil {
+			return err
+		}
+
+		// image is kept for the cleanup below, Create returns nil when the insert fails
+		created, err = s.repo.Create(image, tx)
+		return err
+	})
+	if err != nil {
+		s.removeFiles(image)
+		return nil, err
+	}
+
+	s.withURLs(created)
+	return created, nil
+}
+
+// ReorderImages takes every image id of the product in the new order
+func (s *ProductImageService) ReorderImages(productID string, imageIDs []string, sub string, role string) ([]*domain.ProductImage, error) {
+	if err := s.ensureCanEdit(productID, sub, role); err != nil {
+		return nil, err
+	}
+
+	var images []*domain.ProductImage
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		current, err := s.repo.FindByProductID(productID, tx)
+		if err != nil {
+			return err
+		}
+
+		if len(current) != len(imageIDs) {
+			return domain.ErrInvalidImageOrder
+		}
+		for _, image := range current {
+			if !slices.Contains(imageIDs, image.ID) {
+				return domain.ErrInvalidImageOrder
+			}
+		}
+
+		if err := s.repo.SetPositions(productID, imageIDs, tx); err != nil {
+			return err
+		}
+
+		images, err = s.repo.FindByProductID(productID, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.withURLs(images...)
+	return images, nil
+}
+
+// DeleteImage removes the row first, a file left behind by a failed storage delete is only logged
+func (s *ProductImageService) DeleteImage(productID string, imageID string, sub string, role string) (bool, error) {
+	if err := s.ensureCanEdit(productID, sub, role); err != nil {
+		return false, err
+	}
+
+	image, err := s.repo.FindByID(imageID, false, nil)
+	if image == nil || err != nil || image.ProductID != productID {
+		return false, domain.ErrImageNotFound
+	}
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	if err := s.repo.DeleteImage(imageID, db); err != nil {
+		return false, err
+	}
+
+	s.removeFiles(image)
+	return true, nil
+}
+
+// only the owner or a role with products:update:any may change the images
+func (s *ProductImageService) ensureCanEdit(productID string, sub string, role string) error {
+	product, err := s.productRepo.FindByID(productID, false, nil)
+	if product == nil || err != nil {
+		return domain.ErrProductNotFound
+	}
+
+	if product.UserID != sub && !s.permissions.HasPermission(role, permissionEnums.ProductsUpdateAny) {
+		return http.ErrForbidden
+	}
+	return nil
+}
+
+func (s *ProductImageService) withURLs(images ...*domain.ProductImage) {
+	for _, image := range images {
+		image.URL = s.storage.URL(image.Key)
+		image.ThumbnailURL = s.storage.URL(image.ThumbnailKey)
+	}
+}
+
+func (s *ProductImageService) removeFiles(image *domain.ProductImage) {
+	ctx := context.Background()
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrInvalidKey) {
+			log.Println("Failed to delete stored file", key, ":", err)
+		}
+	}
+}
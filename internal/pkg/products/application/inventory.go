@@ -0,0 +1,118 @@
+package application
+
+import (
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/enums"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// the route requires inventory:read, deleted products keep their history
+func (s *ProductService) GetStockHistory(productID string, query *dtos.StockHistoryQueryDto) (*types.Paginated[domain.InventoryMovement], error) {
+	product, err := s.repo.FindByID(productID, true, nil)
+	if product == nil || err != nil {
+		return nil, domain.ErrProductNotFound
+	}
+
+	return s.repo.FindMovements(productID, query.VariantID, query.Page, query.Limit, query.Cursor, nil)
+}
+
+// AdjustStock posts a manual movement, the route requires inventory:adjust
+func (s *ProductService) AdjustStock(productID string, adjustment *dtos.AdjustStockDTO, sub string) (*domain.InventoryMovement, error) {
+	reason := enums.MovementReason(adjustment.Reason)
+	if !reason.IsManual() || adjustment.Quantity == 0 {
+		return nil, domain.ErrInvalidMovement
+	}
+
+	var movement *domain.InventoryMovement
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// products are locked before their variants, the order orders reserve stock in
+		locked, err := s.repo.FindByIDsForUpdate([]string{productID}, false, tx)
+		if err != nil {
+			return err
+		}
+		if len(locked) == 0 {
+			return domain.ErrProductNotFound
+		}
+
+		variantID := adjustment.VariantID
+		if variantID == "" {
+			variant, err := s.singleVariant(productID, tx)
+			if err != nil {
+				return err
+			}
+			variantID = variant.ID
+		}
+
+		movement, err = s.repo.AdjustStock(productID, variantID, adjustment.Quantity, reason, adjustment.Note, tx)
+		if err != nil {
+			return err
+		}
+		if movement != nil {
+			return nil
+		}
+
+		// either the variant is not a live variant of the product or the stock would go negative
+		variants, err := s.repo.FindVariants([]string{productID}, tx)
+		if err != nil {
+			return err
+		}
+		for _, variant := range variants {
+			if variant.ID == variantID {
+				return domain.ErrInvalidStock
+			}
+		}
+		return domain.ErrVariantNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// setSingleVariantStock sets the stock of a product that has one variant, the caller holds the product row
+func (s *ProductService) setSingleVariantStock(productID string, stock int, tx *gorm.DB) error {
+	if stock < 0 {
+		return domain.ErrInvalidStock
+	}
+
+	variant, err := s.singleVariant(productID, tx)
+	if err != nil {
+		return err
+	}
+
+	if stock == variant.Stock {
+		return nil
+	}
+
+	movement, err := s.repo.AdjustStock(productID, variant.ID, stock-variant.Stock, enums.Adjustment, "set through the product", tx)
+	if err != nil {
+		return err
+	}
+	if movement == nil {
+		return domain.ErrInvalidStock
+	}
+	return nil
+}
+
+func (s *ProductService) singleVariant(productID string, tx *gorm.DB) (*domain.ProductVariant, error) {
+	variants, err := s.repo.FindVariants([]string{productID}, tx)
+	if err != nil {
+		return nil, err
+	}
+	switch len(variants) {
+	case 0:
+		return nil, domain.ErrVariantNotFound
+	case 1:
+		return variants[0], nil
+	default:
+		return nil, domain.ErrProductHasVariants
+	}
+}
@@ -1,11 +1,17 @@
 package application
 
 import (
+	"strings"
+
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
 	"github.com/QuangNV23062004/learning-go/internal/http"
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionEnums "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/products/dtos"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
-	roleEnums "github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
+	productTypes "github.com/QuangNV23062004/learning-go/internal/pkg/products/types"
 	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/types"
 
@@ -13,14 +19,16 @@ import (
 )
 
 type ProductService struct {
-	repo     *infrastructure.ProductRepository
-	userRepo *userInfrastructure.UserRepository
+	repo        *infrastructure.ProductRepository
+	userRepo    *userInfrastructure.UserRepository
+	permissions *permissionApplication.PermissionService
 }
 
-func NewProductService(repo *infrastructure.ProductRepository, userRepo *userInfrastructure.UserRepository) *ProductService {
+func NewProductService(repo *infrastructure.ProductRepository, userRepo *userInfrastructure.UserRepository, permissions *permissionApplication.PermissionService) *ProductService {
 	return &ProductService{
-		repo:     repo,
-		userRepo: userRepo,
+		repo:        repo,
+		userRepo:    userRepo,
+		permissions: permissions,
 	}
 }
 
@@ -28,7 +36,7 @@ func (s *ProductService) GetProductByID(id string, includeDeleted bool, role str
 
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.ProductsReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -40,6 +48,13 @@ func (s *ProductService) GetProductByID(id string, includeDeleted bool, role str
 	if err != nil {
 		return nil, err
 	}
+
+	if err := s.repo.AttachLabels([]*domain.Product{product}, nil); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AttachVariants([]*domain.Product{product}, nil); err != nil {
+		return nil, err
+	}
 	return product, nil
 }
 
@@ -52,7 +67,7 @@ func (s *ProductService) GetProductsByUserID(userID string, includeDeleted bool,
 
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.ProductsReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -63,7 +78,7 @@ func (s *ProductService) GetProductsByUserID(userID string, includeDeleted bool,
 	return products, nil
 }
 
-func (s *ProductService) GetPaginatedProductsByUserID(userID string, page int, limit int, search, searchField, order, sortBy string, includeDeleted bool, role string) (*types.Paginated[domain.Product], error) {
+func (s *ProductService) GetPaginatedProductsByUserID(userID string, page int, limit int, cursor, search, searchField, order, sortBy string, filters []types.Filter, includeDeleted bool, role string) (*types.Paginated[domain.Product], error) {
 	users, err := s.userRepo.FindByID(userID, false, nil)
 	if users == nil {
 		return nil, domain.ErrUserNotFound
@@ -71,14 +86,22 @@ func (s *ProductService) GetPaginatedProductsByUserID(userID string, page int, l
 
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.ProductsReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
-	paginatedProducts, err := s.repo.PaginatedByUserId(userID, page, limit, search, searchField, order, sortBy, safeIncludeDeleted, nil)
+	paginatedProducts, err := s.repo.PaginatedByUserId(userID, page, limit, cursor, search, searchField, order, sortBy, filters, safeIncludeDeleted, nil)
 	if err != nil {
 		return nil, err
 	}
+
+	products := make([]*domain.Product, len(paginatedProducts.Data))
+	for i := range paginatedProducts.Data {
+		products[i] = &paginatedProducts.Data[i]
+	}
+	if err := s.repo.AttachLabels(products, nil); err != nil {
+		return nil, err
+	}
 	return paginatedProducts, nil
 }
 
@@ -86,7 +109,7 @@ func (s *ProductService) GetAllProducts(includeDeleted bool, role string) ([]*do
 
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.ProductsReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -98,22 +121,34 @@ func (s *ProductService) GetAllProducts(includeDeleted bool, role string) ([]*do
 	return products, nil
 }
 
-func (s *ProductService) GetPaginatedProducts(page int, limit int, search, searchField, order, sortBy string, includeDeleted bool, role string) (*types.Paginated[*domain.Product], error) {
+func (s *ProductService) GetPaginatedProducts(page int, limit int, cursor, search, searchField, order, sortBy string, filters []types.Filter, includeDeleted bool, role string) (*types.Paginated[*domain.Product], error) {
 	safeIncludeDeleted := false
 
-	if role == string(roleEnums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.ProductsReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
-	paginatedProducts, err := s.repo.Paginated(page, limit, search, searchField, order, sortBy, safeIncludeDeleted, nil)
+	paginatedProducts, err := s.repo.Paginated(page, limit, cursor, search, searchField, order, sortBy, filters, safeIncludeDeleted, nil)
 	if err != nil {
 		return nil, err
 	}
+
+	if err := s.repo.AttachLabels(paginatedProducts.Data, nil); err != nil {
+		return nil, err
+	}
 	return paginatedProducts, nil
 }
 
+// search only ever returns live products, so it needs no role
+func (s *ProductService) SearchProducts(query *dtos.SearchProductsQueryDto) (*types.Paginated[*productTypes.ProductSearchResult], error) {
+	if strings.TrimSpace(query.Q) == "" {
+		return nil, http.ErrInvalidQuery
+	}
+	return s.repo.Search(query.Q, query.Page, query.Limit, nil)
+}
+
 func (s *ProductService) CreateProduct(product *dtos.CreateProductDTO) (*domain.Product, error) {
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), product.UserID)
 	var createdProduct *domain.Product
 	err := db.Transaction(func(tx *gorm.DB) error {
 		user, err := s.userRepo.FindByID(product.UserID, false, tx)
@@ -127,10 +162,14 @@ func (s *ProductService) CreateProduct(product *dtos.CreateProductDTO) (*domain.
 		}
 
 		newProduct := &domain.Product{
-			Name:   product.Name,
-			Price:  product.Price,
-			Stock:  product.Stock,
-			UserID: product.UserID,
+			Name:     product.Name,
+			Price:    product.Price,
+			Currency: strings.ToUpper(product.Currency),
+			UserID:   product.UserID,
+		}
+
+		if newProduct.Currency == "" {
+			newProduct.Currency = domain.DefaultCurrency
 		}
 
 		createdProduct, err = s.repo.Create(newProduct, tx)
@@ -138,7 +177,35 @@ func (s *ProductService) CreateProduct(product *dtos.CreateProductDTO) (*domain.
 			return err
 		}
 
-		return nil
+		// the opening stock of every variant is posted to the ledger, a product sent without variants gets a single one
+		variantDtos := product.Variants
+		if len(variantDtos) == 0 {
+			sku := product.SKU
+			if sku == "" {
+				sku = createdProduct.ID
+			}
+			variantDtos = []dtos.VariantDTO{{SKU: sku, Stock: product.Stock}}
+		}
+		if err := s.saveVariants(createdProduct.ID, product.Options, variantDtos, tx); err != nil {
+			return err
+		}
+
+		createdProduct, err = s.repo.FindByID(createdProduct.ID, false, tx)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.AttachVariants([]*domain.Product{createdProduct}, tx); err != nil {
+			return err
+		}
+
+		if err := s.repo.ReplaceCategories(createdProduct.ID, product.CategoryIDs, tx); err != nil {
+			return err
+		}
+		if err := s.repo.ReplaceTags(createdProduct.ID, product.Tags, tx); err != nil {
+			return err
+		}
+
+		return s.repo.AttachLabels([]*domain.Product{createdProduct}, tx)
 	})
 	if err != nil {
 		return nil, err
@@ -147,11 +214,11 @@ func (s *ProductService) CreateProduct(product *dtos.CreateProductDTO) (*domain.
 	return createdProduct, nil
 }
 
-func (s *ProductService) UpdateProduct(id string, product *dtos.UpdateProductDTO, UserID string, role string) (*domain.Product, error) {
+func (s *ProductService) UpdateProduct(id string, product *dtos.UpdateProductDTO, UserID string, role string, version int) (*domain.Product, error) {
 
 	var updatedProduct *domain.Product
 
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), UserID)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 
@@ -165,10 +232,14 @@ func (s *ProductService) UpdateProduct(id string, product *dtos.UpdateProductDTO
 			return err
 		}
 
-		if (UserID != existingProduct.UserID) && (role != string(roleEnums.Admin)) {
+		if UserID != existingProduct.UserID && !s.permissions.HasPermission(role, permissionEnums.ProductsUpdateAny) {
 			return http.ErrForbidden
 		}
 
+		if !existingProduct.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
 		if product.Name != "" {
 			existingProduct.Name = product.Name
 		}
@@ -177,8 +248,8 @@ func (s *ProductService) UpdateProduct(id string, product *dtos.UpdateProductDTO
 			existingProduct.Price = product.Price
 		}
 
-		if product.Stock != 0 {
-			existingProduct.Stock = product.Stock
+		if product.Currency != "" {
+			existingProduct.Currency = strings.ToUpper(product.Currency)
 		}
 
 		updatedProduct, err = s.repo.Update(existingProduct, tx)
@@ -187,7 +258,33 @@ func (s *ProductService) UpdateProduct(id string, product *dtos.UpdateProductDTO
 			return err
 		}
 
-		return nil
+		// with a single variant the product stock is that variant's stock, the difference goes to the ledger
+		if product.Stock != nil {
+			if err := s.setSingleVariantStock(id, *product.Stock, tx); err != nil {
+				return err
+			}
+
+			updatedProduct, err = s.repo.FindByID(id, false, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		if product.CategoryIDs != nil {
+			if err := s.repo.ReplaceCategories(id, *product.CategoryIDs, tx); err != nil {
+				return err
+			}
+		}
+		if product.Tags != nil {
+			if err := s.repo.ReplaceTags(id, *product.Tags, tx); err != nil {
+				return err
+			}
+		}
+
+		if err := s.repo.AttachLabels([]*domain.Product{updatedProduct}, tx); err != nil {
+			return err
+		}
+		return s.repo.AttachVariants([]*domain.Product{updatedProduct}, tx)
 	})
 
 	if err != nil {
@@ -196,9 +293,9 @@ func (s *ProductService) UpdateProduct(id string, product *dtos.UpdateProductDTO
 	return updatedProduct, nil
 }
 
-func (s *ProductService) DeleteProduct(id string, role string, UserID string) (bool, error) {
+func (s *ProductService) DeleteProduct(id string, role string, UserID string, version int) (bool, error) {
 	var deleted bool
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), UserID)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 		existingProduct, err := s.repo.FindByID(id, false, tx)
@@ -210,10 +307,14 @@ func (s *ProductService) DeleteProduct(id string, role string, UserID string) (b
 			return err
 		}
 
-		if (UserID != existingProduct.UserID) && (role != string(roleEnums.Admin)) {
+		if UserID != existingProduct.UserID && !s.permissions.HasPermission(role, permissionEnums.ProductsDeleteAny) {
 			return http.ErrForbidden
 		}
 
+		if !existingProduct.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
 		deleted, err = s.repo.Delete(id, tx)
 		if err != nil {
 			return err
@@ -228,9 +329,9 @@ func (s *ProductService) DeleteProduct(id string, role string, UserID string) (b
 
 }
 
-func (s *ProductService) RestoreProduct(id string) (bool, error) {
+func (s *ProductService) RestoreProduct(id string, sub string, version int) (bool, error) {
 	var restored bool
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 	err := db.Transaction(func(tx *gorm.DB) error {
 		deletedProduct, err := s.repo.FindByID(id, true, tx)
 		if deletedProduct == nil {
@@ -239,6 +340,9 @@ func (s *ProductService) RestoreProduct(id string) (bool, error) {
 		if err != nil {
 			return err
 		}
+		if !deletedProduct.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
 		restored, err = s.repo.Restore(id, tx)
 		if err != nil {
 			return err
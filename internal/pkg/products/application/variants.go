@@ -0,0 +1,243 @@
+package application
+
+import (
+	"slices"
+	"strings"
+
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
+	"github.com/QuangNV23062004/learning-go/internal/http"
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	permissionEnums "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/enums"
+	productTypes "github.com/QuangNV23062004/learning-go/internal/pkg/products/types"
+
+	"gorm.io/gorm"
+)
+
+func (s *ProductService) GetVariants(productID string) (*productTypes.VariantSet, error) {
+	product, err := s.repo.FindByID(productID, false, nil)
+	if product == nil || err != nil {
+		return nil, domain.ErrProductNotFound
+	}
+
+	if err := s.repo.AttachVariants([]*domain.Product{product}, nil); err != nil {
+		return nil, err
+	}
+	return &productTypes.VariantSet{Version: product.Version, Options: product.Options, Variants: product.Variants}, nil
+}
+
+// ReplaceVariants sets the options and variants of the product in one go, only the owner or a role
+// with products:update:any may do it. The product stock is recomputed from the new variants
+func (s *ProductService) ReplaceVariants(productID string, variantsDto *dtos.ReplaceVariantsDTO, sub string, role string, version int) (*productTypes.VariantSet, error) {
+	var product *domain.Product
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// the row lock keeps orders from reserving variants while they are replaced
+		locked, err := s.repo.FindByIDsForUpdate([]string{productID}, false, tx)
+		if err != nil {
+			return err
+		}
+		if len(locked) == 0 {
+			return domain.ErrProductNotFound
+		}
+		product = locked[0]
+
+		if sub != product.UserID && !s.permissions.HasPermission(role, permissionEnums.ProductsUpdateAny) {
+			return http.ErrForbidden
+		}
+
+		if !product.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		if err := s.saveVariants(product.ID, variantsDto.Options, variantsDto.Variants, tx); err != nil {
+			return err
+		}
+
+		if err := s.repo.SyncStock(product.ID, tx); err != nil {
+			return err
+		}
+
+		product, err = s.repo.FindByID(product.ID, false, tx)
+		if err != nil {
+			return err
+		}
+		return s.repo.AttachVariants([]*domain.Product{product}, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &productTypes.VariantSet{Version: product.Version, Options: product.Options, Variants: product.Variants}, nil
+}
+
+// saveVariants replaces the options, updates the variants sent with an id, creates the ones without
+// and retires the live variants that were left out
+func (s *ProductService) saveVariants(productID string, optionDtos []dtos.OptionDTO, variantDtos []dtos.VariantDTO, tx *gorm.DB) error {
+	if len(variantDtos) == 0 {
+		return domain.ErrNoVariants
+	}
+
+	options, err := buildOptions(optionDtos)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.repo.FindVariants([]string{productID}, tx)
+	if err != nil {
+		return err
+	}
+	existingByID := make(map[string]*domain.ProductVariant, len(existing))
+	for _, variant := range existing {
+		existingByID[variant.ID] = variant
+	}
+
+	variants := make([]*domain.ProductVariant, 0, len(variantDtos))
+	skus := make([]string, 0, len(variantDtos))
+	keptIDs := make([]string, 0, len(variantDtos))
+	combinations := map[string]bool{}
+
+	for _, variantDto := range variantDtos {
+		sku := strings.TrimSpace(variantDto.SKU)
+		if sku == "" {
+			return domain.ErrInvalidSKU
+		}
+		if slices.Contains(skus, sku) {
+			return domain.ErrSKUTaken
+		}
+		if variantDto.Stock < 0 {
+			return domain.ErrInvalidStock
+		}
+		if variantDto.Price != nil && *variantDto.Price <= 0 {
+			return domain.ErrInvalidPrice
+		}
+
+		values, combination, err := variantOptions(options, variantDto.Options)
+		if err != nil {
+			return err
+		}
+		if combinations[combination] {
+			return domain.ErrDuplicateVariant
+		}
+		combinations[combination] = true
+
+		variant := &domain.ProductVariant{ProductID: productID}
+		if variantDto.ID != "" {
+			stored, ok := existingByID[variantDto.ID]
+			if !ok || slices.Contains(keptIDs, variantDto.ID) {
+				return domain.ErrVariantNotFound
+			}
+			variant = stored
+			keptIDs = append(keptIDs, stored.ID)
+		}
+
+		// a copy so the stored stock is still known when the change is posted
+		next := *variant
+		next.SKU = sku
+		next.Options = values
+		next.Price = variantDto.Price
+		next.Stock = variantDto.Stock
+
+		variants = append(variants, &next)
+		skus = append(skus, sku)
+	}
+
+	taken, err := s.repo.SKUTaken(skus, keptIDs, tx)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return domain.ErrSKUTaken
+	}
+
+	if err := s.repo.ReplaceOptions(productID, options, tx); err != nil {
+		return err
+	}
+
+	for _, variant := range existing {
+		if !slices.Contains(keptIDs, variant.ID) {
+			if err := s.repo.RetireVariant(variant.ID, tx); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, variant := range variants {
+		// stock set through the list is posted to the ledger as the difference to the current stock
+		change := variant.Stock
+		reason := enums.Restock
+		if variant.ID != "" {
+			change -= existingByID[variant.ID].Stock
+			reason = enums.Adjustment
+			err = s.repo.UpdateVariant(variant, tx)
+		} else {
+			err = s.repo.CreateVariant(variant, tx)
+		}
+		if err != nil {
+			return err
+		}
+
+		if change != 0 {
+			movement, err := s.repo.AdjustStock(productID, variant.ID, change, reason, "set with the variant list", tx)
+			if err != nil {
+				return err
+			}
+			if movement == nil {
+				return domain.ErrInvalidStock
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildOptions trims the names and values, names and the values of one option must be unique
+func buildOptions(optionDtos []dtos.OptionDTO) ([]*domain.ProductOption, error) {
+	options := make([]*domain.ProductOption, 0, len(optionDtos))
+	names := make([]string, 0, len(optionDtos))
+
+	for _, optionDto := range optionDtos {
+		name := strings.TrimSpace(optionDto.Name)
+		if name == "" || slices.Contains(names, name) || len(optionDto.Values) == 0 {
+			return nil, domain.ErrInvalidOption
+		}
+
+		values := make([]string, 0, len(optionDto.Values))
+		for _, value := range optionDto.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || slices.Contains(values, value) {
+				return nil, domain.ErrInvalidOption
+			}
+			values = append(values, value)
+		}
+
+		names = append(names, name)
+		options = append(options, &domain.ProductOption{Name: name, Values: values})
+	}
+	return options, nil
+}
+
+// variantOptions checks the variant picks one allowed value of every option and nothing else,
+// the combination key identifies the variant among its siblings
+func variantOptions(options []*domain.ProductOption, picked map[string]string) (map[string]string, string, error) {
+	if len(picked) != len(options) {
+		return nil, "", domain.ErrInvalidVariant
+	}
+
+	values := make(map[string]string, len(options))
+	parts := make([]string, 0, len(options))
+	for _, option := range options {
+		value, ok := picked[option.Name]
+		value = strings.TrimSpace(value)
+		if !ok || !slices.Contains(option.Values, value) {
+			return nil, "", domain.ErrInvalidVariant
+		}
+		values[option.Name] = value
+		parts = append(parts, value)
+	}
+	return values, strings.Join(parts, "\x00"), nil
+}
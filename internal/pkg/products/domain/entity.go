@@ -4,14 +4,32 @@ import (
 	"github.com/QuangNV23062004/learning-go/internal/domain"
 )
 
+const DefaultCurrency = "USD"
+
 type Product struct {
-	Name   string  `json:"name" gorm:"not null"`
-	Price  float64 `json:"price" gorm:"not null;default:0"`
-	Stock  int     `json:"stock" gorm:"not null;default:0"`
-	UserID string  `json:"user_id" gorm:"type:uuid;not null"`
+	Name     string  `json:"name" gorm:"not null"`
+	Price    float64 `json:"price" gorm:"not null;default:0"`
+	Currency string  `json:"currency" gorm:"type:char(3);not null;default:'USD'"`
+	UserID   string  `json:"user_id" gorm:"type:uuid;not null"`
+
+	// total stock of the active variants, kept in step with every variant stock change
+	Stock int `json:"stock" gorm:"not null;default:0"`
+
+	// loaded from the join tables, see ProductRepository.AttachLabels
+	CategoryIDs []string `json:"category_ids,omitempty" gorm:"-"`
+	Tags        []string `json:"tags,omitempty" gorm:"-"`
+
+	// loaded on the single product reads, see ProductRepository.AttachVariants
+	Options  []*ProductOption  `json:"options,omitempty" gorm:"-"`
+	Variants []*ProductVariant `json:"variants,omitempty" gorm:"-"`
+
 	domain.BaseEntity
 }
 
 func (p *Product) GetBaseEntity() *domain.BaseEntity {
 	return &p.BaseEntity
 }
+
+func (p *Product) AuditType() string {
+	return "product"
+}
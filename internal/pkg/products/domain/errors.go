@@ -5,4 +5,22 @@ import "errors"
 var (
 	ErrProductNotFound = errors.New("product not found")
 	ErrUserNotFound    = errors.New("user not found")
+	ErrUnknownCategory = errors.New("unknown category")
+
+	ErrVariantNotFound    = errors.New("variant not found")
+	ErrInvalidVariant     = errors.New("a variant must pick one allowed value of every product option")
+	ErrInvalidOption      = errors.New("options need a unique name and at least one unique value")
+	ErrDuplicateVariant   = errors.New("two variants have the same options")
+	ErrSKUTaken           = errors.New("sku is already in use")
+	ErrInvalidSKU         = errors.New("sku must not be empty")
+	ErrInvalidStock       = errors.New("stock must not be negative")
+	ErrInvalidPrice       = errors.New("price must be greater than zero")
+	ErrNoVariants         = errors.New("a product needs at least one variant")
+	ErrProductHasVariants = errors.New("stock of a product with several variants is set per variant")
+	ErrInvalidMovement    = errors.New("a manual movement needs a non zero quantity and the reason adjustment or restock")
+
+	ErrImageNotFound     = errors.New("image not found")
+	ErrImageTooLarge     = errors.New("image is too large")
+	ErrUnsupportedImage  = errors.New("only jpeg, png and gif images are supported")
+	ErrInvalidImageOrder = errors.New("image order must list every image of the product once")
 )
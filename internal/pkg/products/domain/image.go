@@ -0,0 +1,25 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// ProductImage is the metadata of an uploaded image, the files themselves live in storage
+type ProductImage struct {
+	ProductID    string `json:"product_id" gorm:"type:uuid;not null;index"`
+	Position     int    `json:"position" gorm:"not null;default:0"`
+	Key          string `json:"-" gorm:"not null"`
+	ThumbnailKey string `json:"-" gorm:"not null"`
+	ContentType  string `json:"content_type" gorm:"not null"`
+	Size         int64  `json:"size" gorm:"not null"`
+	Width        int    `json:"width" gorm:"not null"`
+	Height       int    `json:"height" gorm:"not null"`
+
+	// filled from the storage keys when the image is returned
+	URL          string `json:"url" gorm:"-"`
+	ThumbnailURL string `json:"thumbnail_url" gorm:"-"`
+
+	domain.BaseEntity
+}
+
+func (i *ProductImage) GetBaseEntity() *domain.BaseEntity {
+	return &i.BaseEntity
+}
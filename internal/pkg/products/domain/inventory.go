@@ -0,0 +1,22 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// InventoryMovement is one change to the stock of a variant, rows are only ever added.
+// Quantity is signed and StockAfter is the variant stock right after the change,
+// the stock of a variant is the sum of its movements
+type InventoryMovement struct {
+	ProductID  string  `json:"product_id" gorm:"type:uuid;not null;index"`
+	VariantID  string  `json:"variant_id" gorm:"type:uuid;not null;index"`
+	Quantity   int     `json:"quantity" gorm:"not null"`
+	StockAfter int     `json:"stock_after" gorm:"not null"`
+	Reason     string  `json:"reason" gorm:"not null;index"`
+	OrderID    *string `json:"order_id,omitempty" gorm:"type:uuid;index"`
+	ActorID    *string `json:"actor_id,omitempty" gorm:"type:uuid"`
+	Note       string  `json:"note" gorm:"not null;default:''"`
+	domain.BaseEntity
+}
+
+func (m *InventoryMovement) GetBaseEntity() *domain.BaseEntity {
+	return &m.BaseEntity
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// Tag is a free form label, names are stored lower case so "Red" and "red" are one tag
+type Tag struct {
+	Name string `json:"name" gorm:"not null;uniqueIndex"`
+	domain.BaseEntity
+}
+
+func (t *Tag) GetBaseEntity() *domain.BaseEntity {
+	return &t.BaseEntity
+}
+
+type ProductTag struct {
+	ProductID string `json:"product_id" gorm:"type:uuid;not null;uniqueIndex:idx_product_tag"`
+	TagID     string `json:"tag_id" gorm:"type:uuid;not null;uniqueIndex:idx_product_tag;index"`
+	domain.BaseEntity
+}
+
+func (p *ProductTag) GetBaseEntity() *domain.BaseEntity {
+	return &p.BaseEntity
+}
+
+type ProductCategory struct {
+	ProductID  string `json:"product_id" gorm:"type:uuid;not null;uniqueIndex:idx_product_category"`
+	CategoryID string `json:"category_id" gorm:"type:uuid;not null;uniqueIndex:idx_product_category;index"`
+	domain.BaseEntity
+}
+
+func (p *ProductCategory) GetBaseEntity() *domain.BaseEntity {
+	return &p.BaseEntity
+}
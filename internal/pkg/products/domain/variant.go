@@ -0,0 +1,39 @@
+package domain
+
+import "github.com/QuangNV23062004/learning-go/internal/domain"
+
+// ProductOption is one axis a product varies on, e.g. size with the values S, M and L
+type ProductOption struct {
+	ProductID string   `json:"product_id" gorm:"type:uuid;not null;uniqueIndex:idx_product_option"`
+	Name      string   `json:"name" gorm:"not null;uniqueIndex:idx_product_option"`
+	Values    []string `json:"values" gorm:"type:jsonb;serializer:json;not null"`
+	Position  int      `json:"position" gorm:"not null;default:0"`
+	domain.BaseEntity
+}
+
+func (o *ProductOption) GetBaseEntity() *domain.BaseEntity {
+	return &o.BaseEntity
+}
+
+// ProductVariant is what gets ordered: one value of every product option, its own SKU and stock.
+// A product without options has a single variant with no option values
+type ProductVariant struct {
+	ProductID string            `json:"product_id" gorm:"type:uuid;not null;index"`
+	SKU       string            `json:"sku" gorm:"not null;uniqueIndex"`
+	Options   map[string]string `json:"options" gorm:"type:jsonb;serializer:json;not null"`
+	Price     *float64          `json:"price"`
+	Stock     int               `json:"stock" gorm:"not null;default:0"`
+	domain.BaseEntity
+}
+
+func (v *ProductVariant) GetBaseEntity() *domain.BaseEntity {
+	return &v.BaseEntity
+}
+
+// UnitPrice is the variant price when it overrides the product price
+func (v *ProductVariant) UnitPrice(product *Product) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
@@ -1,8 +1,17 @@
 package dtos
 
 type CreateProductDTO struct {
-	Name   string  `json:"name" binding:"required"`
-	Price  float64 `json:"price" binding:"required,gt=0"`
-	Stock  int     `json:"stock" binding:"required,gte=0"`
-	UserID string  `json:"user_id" binding:"required,uuid"`
+	Name     string  `json:"name" binding:"required"`
+	Price    float64 `json:"price" binding:"required,gt=0"`
+	Currency string  `json:"currency" binding:"omitempty,len=3"`
+	Stock    int     `json:"stock" binding:"omitempty,gte=0"`
+	UserID   string  `json:"user_id" binding:"required,uuid"`
+
+	CategoryIDs []string `json:"category_ids" binding:"omitempty,dive,uuid"`
+	Tags        []string `json:"tags" binding:"omitempty,dive,max=50"`
+
+	// without variants the product gets a single variant holding Stock, SKU defaults to the product id
+	SKU      string       `json:"sku" binding:"omitempty"`
+	Options  []OptionDTO  `json:"options" binding:"omitempty,dive"`
+	Variants []VariantDTO `json:"variants" binding:"omitempty,dive"`
 }
@@ -0,0 +1,5 @@
+package dtos
+
+type ReorderImagesDTO struct {
+	ImageIDs []string `json:"image_ids" binding:"required,dive,uuid"`
+}
@@ -0,0 +1,25 @@
+package dtos
+
+type AdjustStockDTO struct {
+	// may be left out when the product has a single variant
+	VariantID string `json:"variant_id" binding:"omitempty,uuid"`
+	Quantity  int    `json:"quantity" binding:"required,ne=0"`
+	Reason    string `json:"reason" binding:"required,oneof=adjustment restock"`
+	Note      string `json:"note" binding:"omitempty,max=500"`
+}
+
+type StockHistoryQueryDto struct {
+	VariantID string `query:"variant_id" validate:"omitempty,uuid"`
+	Page      int    `query:"page" validate:"omitempty,min=1"`
+	Limit     int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor    string `query:"cursor" validate:"omitempty,max=512"`
+}
+
+func (q *StockHistoryQueryDto) ApplyDefaults() {
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.Limit == 0 {
+		q.Limit = 20
+	}
+}
@@ -0,0 +1,16 @@
+package dtos
+
+type SearchProductsQueryDto struct {
+	Q     string `query:"q" validate:"required,max=255"`
+	Page  int    `query:"page" validate:"omitempty,min=1"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+func (q *SearchProductsQueryDto) ApplyDefaults() {
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.Limit == 0 {
+		q.Limit = 5
+	}
+}
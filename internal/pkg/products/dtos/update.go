@@ -1,7 +1,14 @@
 package dtos
 
 type UpdateProductDTO struct {
-	Name  string  `json:"name" binding:"ommitempty"`
-	Price float64 `json:"price" binding:"omitempty,gt=0"`
-	Stock int     `json:"stock" binding:"omitempty,gte=0"`
+	Name     string  `json:"name" binding:"ommitempty"`
+	Price    float64 `json:"price" binding:"omitempty,gt=0"`
+	Currency string  `json:"currency" binding:"omitempty,len=3"`
+
+	// nil keeps the stock, zero is a valid stock
+	Stock *int `json:"stock" binding:"omitempty,gte=0"`
+
+	// nil keeps the current labels, an empty list clears them
+	CategoryIDs *[]string `json:"category_ids" binding:"omitempty,dive,uuid"`
+	Tags        *[]string `json:"tags" binding:"omitempty,dive,max=50"`
 }
@@ -0,0 +1,22 @@
+package dtos
+
+type OptionDTO struct {
+	Name   string   `json:"name" binding:"required"`
+	Values []string `json:"values" binding:"required,min=1"`
+}
+
+type VariantDTO struct {
+	// set to keep an existing variant, empty creates a new one
+	ID      string            `json:"id" binding:"omitempty,uuid"`
+	SKU     string            `json:"sku" binding:"required"`
+	Options map[string]string `json:"options"`
+	Price   *float64          `json:"price" binding:"omitempty,gt=0"`
+	Stock   int               `json:"stock" binding:"gte=0"`
+}
+
+// ReplaceVariantsDTO is the whole option and variant set of a product,
+// live variants missing from Variants are retired
+type ReplaceVariantsDTO struct {
+	Options  []OptionDTO  `json:"options" binding:"omitempty,dive"`
+	Variants []VariantDTO `json:"variants" binding:"required,min=1,dive"`
+}
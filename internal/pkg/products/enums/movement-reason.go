@@ -0,0 +1,15 @@
+package enums
+
+type MovementReason string
+
+const (
+	OrderReserve MovementReason = "order_reserve"
+	OrderRelease MovementReason = "order_release"
+	Adjustment   MovementReason = "adjustment"
+	Restock      MovementReason = "restock"
+)
+
+// only these may be posted by hand, order movements are written by the order service
+func (r MovementReason) IsManual() bool {
+	return r == Adjustment || r == Restock
+}
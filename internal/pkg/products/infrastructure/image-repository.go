@@ -0,0 +1,60 @@
+package infrastructure
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+
+	"gorm.io/gorm"
+)
+
+type ProductImageRepository struct {
+	*infrastructure.BaseRepository[*domain.ProductImage]
+	db *gorm.DB
+}
+
+func NewProductImageRepository(db *gorm.DB) *ProductImageRepository {
+	return &ProductImageRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.ProductImage](db),
+		db:             db,
+	}
+}
+
+func (r *ProductImageRepository) FindByProductID(productID string, tx *gorm.DB) ([]*domain.ProductImage, error) {
+	var images []*domain.ProductImage
+	err := r.GetDatabase(tx).
+		Where("product_id = ?", productID).
+		Order("position asc, created_at asc").
+		Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// NextPosition is one past the last image of the product, 0 for the first one
+func (r *ProductImageRepository) NextPosition(productID string, tx *gorm.DB) (int, error) {
+	var next int
+	err := r.GetDatabase(tx).Model(&domain.ProductImage{}).
+		Where("product_id = ?", productID).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Scan(&next).Error
+	return next, err
+}
+
+// SetPositions stores the index of every id as its position
+func (r *ProductImageRepository) SetPositions(productID string, imageIDs []string, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	for position, id := range imageIDs {
+		err := db.Model(&domain.ProductImage{}).
+			Where("id = ? AND product_id = ?", id, productID).
+			Update("position", position).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ProductImageRepository) DeleteImage(id string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("id = ?", id).Delete(&domain.ProductImage{}).Error
+}
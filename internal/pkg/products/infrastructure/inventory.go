@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/enums"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"gorm.io/gorm"
+)
+
+var movementSort = infrastructure.Sort{{Name: "created_at", Column: "created_at", Desc: true}}
+
+// ReserveStock takes quantity from a live variant of a live product for the order, false when the stock is too low
+func (r *ProductRepository) ReserveStock(productID string, variantID string, quantity int, orderID string, tx *gorm.DB) (bool, error) {
+	movement, err := r.moveStock(productID, variantID, -quantity, enums.OrderReserve, &orderID, "", true, tx)
+	return movement != nil, err
+}
+
+// ReleaseStock gives quantity back to the variant, deleted products and retired variants included.
+// False when the variant no longer exists
+func (r *ProductRepository) ReleaseStock(productID string, variantID string, quantity int, orderID string, tx *gorm.DB) (bool, error) {
+	movement, err := r.moveStock(productID, variantID, quantity, enums.OrderRelease, &orderID, "", false, tx)
+	return movement != nil, err
+}
+
+// AdjustStock posts a manual change to a live variant, nil when it would take the stock below zero
+func (r *ProductRepository) AdjustStock(productID string, variantID string, quantity int, reason enums.MovementReason, note string, tx *gorm.DB) (*domain.InventoryMovement, error) {
+	return r.moveStock(productID, variantID, quantity, reason, nil, note, true, tx)
+}
+
+// moveStock changes the variant stock by quantity and records the movement with the resulting stock.
+// Nothing happens when the stock would go below zero. The product stock follows unless the variant is retired
+func (r *ProductRepository) moveStock(productID string, variantID string, quantity int, reason enums.MovementReason, orderID *string, note string, onlyLive bool, tx *gorm.DB) (*domain.InventoryMovement, error) {
+	db := r.GetDatabase(tx)
+
+	query := `UPDATE product_variants SET stock = stock + ?, version = version + 1, updated_at = ?
+		WHERE id = ? AND product_id = ? AND stock + ? >= 0`
+	if onlyLive {
+		query += ` AND is_deleted = false
+			AND EXISTS (SELECT 1 FROM products WHERE products.id = product_variants.product_id AND products.is_deleted = false)`
+	}
+	query += ` RETURNING stock, is_deleted`
+
+	var moved []struct {
+		Stock     int
+		IsDeleted bool
+	}
+	if err := db.Raw(query, quantity, time.Now(), variantID, productID, quantity).Scan(&moved).Error; err != nil {
+		return nil, err
+	}
+	if len(moved) == 0 {
+		return nil, nil
+	}
+
+	if !moved[0].IsDeleted {
+		err := db.Model(&domain.Product{}).
+			Where("id = ?", productID).
+			Updates(map[string]interface{}{
+				"stock":      gorm.Expr("stock + ?", quantity),
+				"version":    gorm.Expr("version + 1"),
+				"updated_at": time.Now(),
+			}).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	movement := &domain.InventoryMovement{
+		ProductID:  productID,
+		VariantID:  variantID,
+		Quantity:   quantity,
+		StockAfter: moved[0].Stock,
+		Reason:     string(reason),
+		OrderID:    orderID,
+		ActorID:    infrastructure.ActorFrom(db),
+		Note:       note,
+	}
+	if err := db.Create(movement).Error; err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// FindMovements pages through the stock history of the product newest first, optionally of one variant
+func (r *ProductRepository) FindMovements(productID string, variantID string, page int, limit int, cursor string, tx *gorm.DB) (*types.Paginated[domain.InventoryMovement], error) {
+	where := r.GetDatabase(tx).Model(&domain.InventoryMovement{}).Where("product_id = ?", productID)
+	if variantID != "" {
+		where = where.Where("variant_id = ?", variantID)
+	}
+	return infrastructure.Paginate[domain.InventoryMovement](where, nil, page, limit, cursor, movementSort)
+}
@@ -0,0 +1,152 @@
+package infrastructure
+
+import (
+	"slices"
+	"strings"
+
+	categoryDomain "github.com/QuangNV23062004/learning-go/internal/pkg/categories/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReplaceCategories sets the product's categories to exactly the given ones, every id has to be a live category
+func (r *ProductRepository) ReplaceCategories(productID string, categoryIDs []string, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	ids := uniqueStrings(categoryIDs)
+
+	if len(ids) > 0 {
+		var found int64
+		err := db.Model(&categoryDomain.Category{}).
+			Where("id IN ? AND is_deleted = ?", ids, false).
+			Count(&found).Error
+		if err != nil || int(found) != len(ids) {
+			return domain.ErrUnknownCategory
+		}
+	}
+
+	if err := db.Where("product_id = ?", productID).Delete(&domain.ProductCategory{}).Error; err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows := make([]domain.ProductCategory, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, domain.ProductCategory{ProductID: productID, CategoryID: id})
+	}
+	return db.Create(&rows).Error
+}
+
+// ReplaceTags sets the product's tags to exactly the given names, unknown tags are created on the way
+func (r *ProductRepository) ReplaceTags(productID string, names []string, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	names = NormalizeTags(names)
+
+	if err := db.Where("product_id = ?", productID).Delete(&domain.ProductTag{}).Error; err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags := make([]domain.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, domain.Tag{Name: name})
+	}
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&tags).Error; err != nil {
+		return err
+	}
+
+	var tagIDs []string
+	if err := db.Model(&domain.Tag{}).Where("name IN ?", names).Pluck("id", &tagIDs).Error; err != nil {
+		return err
+	}
+
+	rows := make([]domain.ProductTag, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		rows = append(rows, domain.ProductTag{ProductID: productID, TagID: tagID})
+	}
+	return db.Create(&rows).Error
+}
+
+// AttachLabels fills the category ids and tag names of the products with two queries
+func (r *ProductRepository) AttachLabels(products []*domain.Product, tx *gorm.DB) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	db := r.GetDatabase(tx)
+	byID := make(map[string]*domain.Product, len(products))
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		product.CategoryIDs = []string{}
+		product.Tags = []string{}
+		byID[product.ID] = product
+		ids = append(ids, product.ID)
+	}
+
+	var categories []domain.ProductCategory
+	if err := db.Where("product_id IN ?", ids).Order("created_at asc").Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, row := range categories {
+		byID[row.ProductID].CategoryIDs = append(byID[row.ProductID].CategoryIDs, row.CategoryID)
+	}
+
+	var tags []struct {
+		ProductID string
+		Name      string
+	}
+	err := db.Model(&domain.ProductTag{}).
+		Select("product_tags.product_id, tags.name").
+		Joins("JOIN tags ON tags.id = product_tags.tag_id").
+		Where("product_tags.product_id IN ?", ids).
+		Order("tags.name asc").
+		Scan(&tags).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range tags {
+		byID[row.ProductID].Tags = append(byID[row.ProductID].Tags, row.Name)
+	}
+	return nil
+}
+
+// DeleteLabels drops the category and tag links of the products, the tags themselves stay
+func (r *ProductRepository) DeleteLabels(productIDs []string, tx *gorm.DB) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	db := r.GetDatabase(tx)
+	if err := db.Where("product_id IN ?", productIDs).Delete(&domain.ProductCategory{}).Error; err != nil {
+		return err
+	}
+	return db.Where("product_id IN ?", productIDs).Delete(&domain.ProductTag{}).Error
+}
+
+// NormalizeTags trims and lower cases the names and drops empty and repeated ones
+func NormalizeTags(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			normalized = append(normalized, name)
+		}
+	}
+	return uniqueStrings(normalized)
+}
+
+func uniqueStrings(values []string) []string {
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
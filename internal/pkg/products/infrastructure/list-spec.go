@@ -0,0 +1,46 @@
+package infrastructure
+
+import "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+
+// a category filter matches products in the category or anywhere below it
+const (
+	productCategoryMatch = `products.id IN (
+		SELECT product_categories.product_id FROM product_categories
+		WHERE product_categories.category_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT categories.id FROM categories WHERE ?
+				UNION
+				SELECT child.id FROM categories child JOIN subtree ON child.parent_id = subtree.id
+			)
+			SELECT id FROM subtree))`
+	productTagMatch = `products.id IN (
+		SELECT product_tags.product_id FROM product_tags
+		JOIN tags ON tags.id = product_tags.tag_id
+		WHERE ?)`
+)
+
+var productListSpec = infrastructure.ListSpec{
+	Sort: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"name":       "name",
+		"price":      "price",
+		"stock":      "stock",
+	},
+	Search: map[string]infrastructure.FilterField{
+		"name": {Column: "name", Kind: infrastructure.FilterString},
+	},
+	Filters: infrastructure.FilterFields{
+		"name":       {Column: "name", Kind: infrastructure.FilterString},
+		"price":      {Column: "price", Kind: infrastructure.FilterNumber},
+		"currency":   {Column: "currency", Kind: infrastructure.FilterString},
+		"stock":      {Column: "stock", Kind: infrastructure.FilterNumber},
+		"user_id":    {Column: "user_id", Kind: infrastructure.FilterUUID},
+		"created_at": {Column: "created_at", Kind: infrastructure.FilterTime},
+		"updated_at": {Column: "updated_at", Kind: infrastructure.FilterTime},
+
+		"category":      {Column: "categories.id", Kind: infrastructure.FilterUUID, Related: productCategoryMatch},
+		"category.slug": {Column: "categories.slug", Kind: infrastructure.FilterString, Related: productCategoryMatch},
+		"tag":           {Column: "tags.name", Kind: infrastructure.FilterString, Related: productTagMatch},
+	},
+}
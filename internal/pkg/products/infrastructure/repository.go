@@ -1,13 +1,12 @@
 package infrastructure
 
 import (
-	"math"
-
 	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
 	"github.com/QuangNV23062004/learning-go/internal/types"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductRepository struct {
@@ -18,7 +17,7 @@ type ProductRepository struct {
 func NewProductRepository(db *gorm.DB) *ProductRepository {
 	return &ProductRepository{
 		db:             db,
-		BaseRepository: infrastructure.NewBaseRepository[*domain.Product](db),
+		BaseRepository: infrastructure.NewBaseRepository[*domain.Product](db).WithListSpec(productListSpec),
 	}
 }
 
@@ -37,41 +36,78 @@ func (r *ProductRepository) FindAllByUserID(userID string, includeDeleted bool,
 	return products, nil
 }
 
-func (r *ProductRepository) PaginatedByUserId(userID string, page int, limit int, search, searchField, order, sortBy string, includeDeleted bool, tx *gorm.DB) (*types.Paginated[domain.Product], error) {
-	var products []domain.Product
+func (r *ProductRepository) PaginatedByUserId(userID string, page int, limit int, cursor, search, searchField, order, sortBy string, filters []types.Filter, includeDeleted bool, tx *gorm.DB) (*types.Paginated[domain.Product], error) {
+	sort, err := productListSpec.ParseSort(sortBy, order)
+	if err != nil {
+		return nil, err
+	}
 
 	where := r.GetDatabase(tx).Model(&domain.Product{}).Where("user_id = ?", userID)
 	if !includeDeleted {
 		where = where.Where("is_deleted = ?", false)
 	}
 
-	if search != "" && searchField != "" {
-		where = where.Where(searchField+" LIKE ?", "%"+search+"%")
+	if where, err = productListSpec.ApplySearch(where, search, searchField); err != nil {
+		return nil, err
+	}
+	if where, err = infrastructure.ApplyFilters(where, filters, productListSpec.Filters); err != nil {
+		return nil, err
 	}
 
-	safeSkip := (page - 1) * limit
+	return infrastructure.Paginate[domain.Product](where, nil, page, limit, cursor, sort)
+}
 
-	error := where.Order(sortBy + " " + order).Offset(safeSkip).Limit(limit).Find(&products).Error
-	if error != nil {
-		return nil, error
+// FindByIDs loads the products without locking them, ids that match nothing are left out
+func (r *ProductRepository) FindByIDs(ids []string, includeDeleted bool, tx *gorm.DB) ([]*domain.Product, error) {
+	var products []*domain.Product
+
+	where := r.GetDatabase(tx).Where("id IN ?", ids)
+	if !includeDeleted {
+		where = where.Where("is_deleted = ?", false)
+	}
+
+	if err := where.Find(&products).Error; err != nil {
+		return nil, err
 	}
 
-	var total int64
-	if err := where.Count(&total).Error; err != nil {
+	return products, nil
+}
+
+// FindByIDsForUpdate locks the product rows until the transaction ends, rows are locked in id order to avoid deadlocks
+func (r *ProductRepository) FindByIDsForUpdate(ids []string, includeDeleted bool, tx *gorm.DB) ([]*domain.Product, error) {
+	var products []*domain.Product
+
+	where := r.GetDatabase(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids)
+	if !includeDeleted {
+		where = where.Where("is_deleted = ?", false)
+	}
+
+	if err := where.Order("id asc").Find(&products).Error; err != nil {
 		return nil, err
 	}
 
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	return products, nil
+}
+
+// PurgeByUserID permanently removes every product of the user, order lines keep their snapshot
+func (r *ProductRepository) PurgeByUserID(userID string, tx *gorm.DB) (int64, error) {
+	db := r.GetDatabase(tx)
 
-	return &types.Paginated[domain.Product]{
-		Data:        products,
-		TotalPages:  totalPages,
-		CurrentPage: page,
-		Limit:       limit,
-		Order:       order,
-		SortBy:      sortBy,
-		HasPrevious: page > 1,
-		HasNext:     page < totalPages,
-	}, nil
+	var productIDs []string
+	if err := db.Model(&domain.Product{}).Where("user_id = ?", userID).Pluck("id", &productIDs).Error; err != nil {
+		return 0, err
+	}
+	if err := r.DeleteLabels(productIDs, db); err != nil {
+		return 0, err
+	}
+	if err := r.DeleteVariants(productIDs, db); err != nil {
+		return 0, err
+	}
+	// only the image rows go, the stored files are left for a storage cleanup
+	if err := db.Where("product_id IN ?", productIDs).Delete(&domain.ProductImage{}).Error; err != nil {
+		return 0, err
+	}
 
+	result := db.Where("user_id = ?", userID).Delete(&domain.Product{})
+	return result.RowsAffected, result.Error
 }
@@ -0,0 +1,93 @@
+package infrastructure
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	productTypes "github.com/QuangNV23062004/learning-go/internal/pkg/products/types"
+	"github.com/QuangNV23062004/learning-go/internal/types"
+
+	"gorm.io/gorm"
+)
+
+// postgres marks the matches with control characters that are stripped from the name first,
+// the name is html escaped before they turn into <mark> tags
+const (
+	headlineStart   = "\x02"
+	headlineStop    = "\x03"
+	headlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", HighlightAll=true`
+)
+
+var headlineTags = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// Search ranks live products whose name contains every word of q, the last letters of a word may
+// be missing. When nothing matches it falls back to trigram similarity so small typos still find
+// the product, the mode only depends on q so every page of a search uses the same one
+func (r *ProductRepository) Search(q string, page int, limit int, tx *gorm.DB) (*types.Paginated[*productTypes.ProductSearchResult], error) {
+	page, limit = infrastructure.ClampPage(page, limit)
+	base := r.GetDatabase(tx).Model(&domain.Product{}).Where("products.is_deleted = ?", false)
+
+	var total int64
+	var matches *gorm.DB
+	if tsQuery := prefixTsQuery(q); tsQuery != "" {
+		matches = base.Session(&gorm.Session{}).
+			Where("products.search_vector @@ to_tsquery('simple', ?)", tsQuery)
+		if err := matches.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		matches = matches.Select(
+			"products.*, ts_rank(products.search_vector, to_tsquery('simple', ?)) AS rank, ts_headline('simple', translate(products.name, ?, ''), to_tsquery('simple', ?), ?) AS snippet",
+			tsQuery, headlineStart+headlineStop, tsQuery, headlineOptions)
+	}
+
+	if total == 0 {
+		matches = base.Session(&gorm.Session{}).Where("? <% products.name", q)
+		if err := matches.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		matches = matches.Select("products.*, word_similarity(?, products.name) AS rank, translate(products.name, ?, '') AS snippet", q, headlineStart+headlineStop)
+	}
+
+	var results []*productTypes.ProductSearchResult
+	if total > 0 {
+		err := matches.Order("rank desc").Order("products.id").
+			Offset((page - 1) * limit).Limit(limit).
+			Find(&results).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, result := range results {
+		result.Snippet = headlineTags.Replace(html.EscapeString(result.Snippet))
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+
+	return &types.Paginated[*productTypes.ProductSearchResult]{
+		Data:        results,
+		TotalPages:  totalPages,
+		CurrentPage: page,
+		Limit:       limit,
+		Order:       "desc",
+		SortBy:      "rank",
+		HasPrevious: page > 1,
+		HasNext:     page < totalPages,
+	}, nil
+}
+
+// prefixTsQuery turns free text into "word:* & word:*", only letters and digits are kept so the
+// user can't inject tsquery operators
+func prefixTsQuery(q string) string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,25 @@
+package infrastructure
+
+import "testing"
+
+func TestPrefixTsQuery(t *testing.T) {
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{q: "shoe", want: "shoe:*"},
+		{q: "  Red   Running shoe ", want: "red:* & running:* & shoe:*"},
+		{q: "usb-c 3.1", want: "usb:* & c:* & 3:* & 1:*"},
+		{q: "café", want: "café:*"},
+		{q: "a & !b | c:* <-> (d)", want: "a:* & b:* & c:* & d:*"},
+		{q: "'; DROP TABLE products; --", want: "drop:* & table:* & products:*"},
+		{q: "!&|", want: ""},
+		{q: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := prefixTsQuery(tt.q); got != tt.want {
+			t.Errorf("prefixTsQuery(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/enums"
+
+	"gorm.io/gorm"
+)
+
+// activeVariantStock is the stock a product shows, the sum over its live variants
+const activeVariantStock = `(SELECT COALESCE(SUM(product_variants.stock), 0) FROM product_variants
+	WHERE product_variants.product_id = products.id AND product_variants.is_deleted = false)`
+
+func (r *ProductRepository) FindOptions(productID string, tx *gorm.DB) ([]*domain.ProductOption, error) {
+	var options []*domain.ProductOption
+	err := r.GetDatabase(tx).
+		Where("product_id = ?", productID).
+		Order("position asc").
+		Find(&options).Error
+	if err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// FindVariants loads the live variants of the products in creation order
+func (r *ProductRepository) FindVariants(productIDs []string, tx *gorm.DB) ([]*domain.ProductVariant, error) {
+	var variants []*domain.ProductVariant
+	err := r.GetDatabase(tx).
+		Where("product_id IN ? AND is_deleted = ?", productIDs, false).
+		Order("created_at asc, id asc").
+		Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// ReplaceOptions sets the product's options to exactly the given ones, in order
+func (r *ProductRepository) ReplaceOptions(productID string, options []*domain.ProductOption, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	if err := db.Where("product_id = ?", productID).Delete(&domain.ProductOption{}).Error; err != nil {
+		return err
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	for position, option := range options {
+		option.ProductID = productID
+		option.Position = position
+	}
+	return db.Create(&options).Error
+}
+
+// CreateVariant inserts the variant without stock, its opening stock is posted to the ledger
+func (r *ProductRepository) CreateVariant(variant *domain.ProductVariant, tx *gorm.DB) error {
+	stock := variant.Stock
+	variant.Stock = 0
+	if err := r.GetDatabase(tx).Create(variant).Error; err != nil {
+		variant.Stock = stock
+		return err
+	}
+	variant.Stock = stock
+	return nil
+}
+
+// UpdateVariant writes the sku, options and price of a live variant, stock only moves through the ledger
+func (r *ProductRepository) UpdateVariant(variant *domain.ProductVariant, tx *gorm.DB) error {
+	variant.Version++
+	result := r.GetDatabase(tx).Model(variant).
+		Where("is_deleted = ?", false).
+		Select("sku", "options", "price", "version", "updated_at").
+		Updates(variant)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrVariantNotFound
+	}
+	return nil
+}
+
+// RetireVariant soft deletes the variant, order lines that point at it keep working
+func (r *ProductRepository) RetireVariant(id string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.ProductVariant{}).
+		Where("id = ? AND is_deleted = ?", id, false).
+		Updates(map[string]interface{}{
+			"is_deleted": true,
+			"deleted_at": time.Now().Format(time.RFC3339),
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// SKUTaken reports whether another variant, retired ones included, already uses one of the skus
+func (r *ProductRepository) SKUTaken(skus []string, exceptIDs []string, tx *gorm.DB) (bool, error) {
+	if len(skus) == 0 {
+		return false, nil
+	}
+
+	where := r.GetDatabase(tx).Model(&domain.ProductVariant{}).Where("sku IN ?", skus)
+	if len(exceptIDs) > 0 {
+		where = where.Where("id NOT IN ?", exceptIDs)
+	}
+
+	var count int64
+	if err := where.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SyncStock recomputes the product stock from its live variants
+func (r *ProductRepository) SyncStock(productID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.Product{}).
+		Where("id = ?", productID).
+		Updates(map[string]interface{}{
+			"stock":      gorm.Expr(activeVariantStock),
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// AttachVariants fills the options and live variants of the products
+func (r *ProductRepository) AttachVariants(products []*domain.Product, tx *gorm.DB) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	db := r.GetDatabase(tx)
+	byID := make(map[string]*domain.Product, len(products))
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		product.Options = []*domain.ProductOption{}
+		product.Variants = []*domain.ProductVariant{}
+		byID[product.ID] = product
+		ids = append(ids, product.ID)
+	}
+
+	var options []*domain.ProductOption
+	if err := db.Where("product_id IN ?", ids).Order("position asc").Find(&options).Error; err != nil {
+		return err
+	}
+	for _, option := range options {
+		byID[option.ProductID].Options = append(byID[option.ProductID].Options, option)
+	}
+
+	variants, err := r.FindVariants(ids, db)
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		byID[variant.ProductID].Variants = append(byID[variant.ProductID].Variants, variant)
+	}
+	return nil
+}
+
+// DeleteVariants permanently drops the options and variants of the products, order lines keep their snapshot.
+// The stock history is append only and stays, a closing movement first takes every variant with stock to zero
+func (r *ProductRepository) DeleteVariants(productIDs []string, tx *gorm.DB) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	db := r.GetDatabase(tx)
+
+	var stocked []*domain.ProductVariant
+	if err := db.Where("product_id IN ? AND stock <> 0", productIDs).Order("id asc").Find(&stocked).Error; err != nil {
+		return err
+	}
+	for _, variant := range stocked {
+		if _, err := r.moveStock(variant.ProductID, variant.ID, -variant.Stock, enums.Adjustment, nil, "closing balance", false, db); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Where("product_id IN ?", productIDs).Delete(&domain.ProductOption{}).Error; err != nil {
+		return err
+	}
+	return db.Where("product_id IN ?", productIDs).Delete(&domain.ProductVariant{}).Error
+}
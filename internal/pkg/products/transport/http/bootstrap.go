@@ -1,23 +1,35 @@
 package http
 
 import (
+	"log"
+
 	"github.com/QuangNV23062004/learning-go/internal/config"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/products/application"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
 	userInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/storage"
 	"github.com/QuangNV23062004/learning-go/internal/utils"
 
 	"github.com/gofiber/fiber/v3"
 	"gorm.io/gorm"
 )
 
-func BootstrapProductRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig) {
+func BootstrapProductRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig, storageConfig *config.StorageConfig) {
 
 	jwtService := utils.NewJwtService(jwtConfig)
 	productRepository := infrastructure.NewProductRepository(db)
 	userRepository := userInfrastructure.NewUserRepository(db)
-	productService := application.NewProductService(productRepository, userRepository)
-	productHandler := NewProductHandler(productService)
-	productRouter := NewRouter(productHandler, jwtService)
+	permissionService := permissionApplication.NewPermissionService(permissionInfrastructure.NewPermissionRepository(db), auditInfrastructure.NewAuditRepository(db))
+	productService := application.NewProductService(productRepository, userRepository, permissionService)
+	fileStorage, err := storage.NewStorage(storageConfig)
+	if err != nil {
+		log.Fatal("Failed to set up storage:", err)
+	}
+	imageService := application.NewProductImageService(infrastructure.NewProductImageRepository(db), productRepository, fileStorage, permissionService, storageConfig.MaxImageSize, storageConfig.ThumbnailSize)
+	productHandler := NewProductHandler(productService, imageService)
+	productRouter := NewRouter(productHandler, jwtService, userRepository, permissionService)
 	productRouter.SetupRoutes(api)
 }
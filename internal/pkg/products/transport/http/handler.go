@@ -10,24 +10,23 @@ import (
 )
 
 type ProductHandler struct {
-	Service *application.ProductService
+	Service       *application.ProductService
+	ImagesService *application.ProductImageService
 }
 
-func NewProductHandler(service *application.ProductService) *ProductHandler {
+func NewProductHandler(service *application.ProductService, imagesService *application.ProductImageService) *ProductHandler {
 	return &ProductHandler{
-		Service: service,
+		Service:       service,
+		ImagesService: imagesService,
 	}
 }
 
 func (h *ProductHandler) GetProductByID(c fiber.Ctx) error {
 	id := c.Params("id")
 	includeDeleted := c.Query("includeDeleted") == "true"
-	role := c.Locals("role")
-	if role == nil {
-		role = ""
-	}
+	role := http.Role(c)
 
-	product, err := h.Service.GetProductByID(id, includeDeleted, role.(string))
+	product, err := h.Service.GetProductByID(id, includeDeleted, role)
 	if err != nil {
 		return err
 	}
@@ -40,11 +39,8 @@ func (h *ProductHandler) GetProductByID(c fiber.Ctx) error {
 func (h *ProductHandler) GetProductsByUserID(c fiber.Ctx) error {
 	userID := c.Params("id")
 	includeDeleted := c.Query("includeDeleted") == "true"
-	role := c.Locals("role")
-	if role == nil {
-		role = ""
-	}
-	products, err := h.Service.GetProductsByUserID(userID, includeDeleted, role.(string))
+	role := http.Role(c)
+	products, err := h.Service.GetProductsByUserID(userID, includeDeleted, role)
 	if err != nil {
 		return err
 	}
@@ -56,10 +52,7 @@ func (h *ProductHandler) GetProductsByUserID(c fiber.Ctx) error {
 func (h *ProductHandler) GetPaginatedProductsByUserID(c fiber.Ctx) error {
 	var query dtos.PaginatedProductsQueryDto
 	userID := c.Params("id")
-	role := c.Locals("role")
-	if role == nil {
-		role = ""
-	}
+	role := http.Role(c)
 	if err := c.Bind().Query(&query); err != nil {
 		err := http.ErrInvalidQuery
 		return err
@@ -68,7 +61,12 @@ func (h *ProductHandler) GetPaginatedProductsByUserID(c fiber.Ctx) error {
 	// Apply defaults
 	query.ApplyDefaults()
 
-	paginatedProducts, err := h.Service.GetPaginatedProductsByUserID(userID, query.Page, query.Limit, query.Search, query.SearchField, query.Order, query.SortBy, query.IncludeDeleted, role.(string))
+	filters, err := http.ParseFilters(c)
+	if err != nil {
+		return err
+	}
+
+	paginatedProducts, err := h.Service.GetPaginatedProductsByUserID(userID, query.Page, query.Limit, query.Cursor, query.Search, query.SearchField, query.Order, query.SortBy, filters, query.IncludeDeleted, role)
 	if err != nil {
 		return err
 	}
@@ -80,11 +78,8 @@ func (h *ProductHandler) GetPaginatedProductsByUserID(c fiber.Ctx) error {
 
 func (h *ProductHandler) GetAllProducts(c fiber.Ctx) error {
 	includeDeleted := c.Query("includeDeleted") == "true"
-	role := c.Locals("role")
-	if role == nil {
-		role = ""
-	}
-	products, err := h.Service.GetAllProducts(includeDeleted, role.(string))
+	role := http.Role(c)
+	products, err := h.Service.GetAllProducts(includeDeleted, role)
 	if err != nil {
 		return err
 	}
@@ -96,10 +91,7 @@ func (h *ProductHandler) GetAllProducts(c fiber.Ctx) error {
 
 func (h *ProductHandler) GetPaginatedProducts(c fiber.Ctx) error {
 	var query dtos.PaginatedProductsQueryDto
-	role := c.Locals("role")
-	if role == nil {
-		role = ""
-	}
+	role := http.Role(c)
 	if err := c.Bind().Query(&query); err != nil {
 		err := http.ErrInvalidQuery
 		return err
@@ -108,7 +100,12 @@ func (h *ProductHandler) GetPaginatedProducts(c fiber.Ctx) error {
 	// Apply defaults
 	query.ApplyDefaults()
 
-	paginatedProducts, err := h.Service.GetPaginatedProducts(query.Page, query.Limit, query.Search, query.SearchField, query.Order, query.SortBy, query.IncludeDeleted, role.(string))
+	filters, err := http.ParseFilters(c)
+	if err != nil {
+		return err
+	}
+
+	paginatedProducts, err := h.Service.GetPaginatedProducts(query.Page, query.Limit, query.Cursor, query.Search, query.SearchField, query.Order, query.SortBy, filters, query.IncludeDeleted, role)
 	if err != nil {
 		return err
 	}
@@ -118,6 +115,24 @@ func (h *ProductHandler) GetPaginatedProducts(c fiber.Ctx) error {
 
 }
 
+func (h *ProductHandler) SearchProducts(c fiber.Ctx) error {
+	var query dtos.SearchProductsQueryDto
+	if err := c.Bind().Query(&query); err != nil {
+		err := http.ErrInvalidQuery
+		return err
+	}
+
+	query.ApplyDefaults()
+
+	results, err := h.Service.SearchProducts(&query)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		results, fiber.StatusOK,
+	))
+}
+
 func (h *ProductHandler) CreateProduct(c fiber.Ctx) error {
 	var body dtos.CreateProductDTO
 	if err := c.Bind().Body(&body); err != nil {
@@ -125,7 +140,11 @@ func (h *ProductHandler) CreateProduct(c fiber.Ctx) error {
 		return err
 	}
 
-	body.UserID = c.Locals("sub").(string)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	body.UserID = sub
 
 	createdProduct, err := h.Service.CreateProduct(&body)
 	if err != nil {
@@ -139,14 +158,22 @@ func (h *ProductHandler) CreateProduct(c fiber.Ctx) error {
 func (h *ProductHandler) UpdateProduct(c fiber.Ctx) error {
 	id := c.Params("id")
 	var body dtos.UpdateProductDTO
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
 	if err := c.Bind().Body(&body); err != nil {
 		err := http.ErrInvalidBody
 		return err
 	}
 
-	updatedProduct, err := h.Service.UpdateProduct(id, &body, sub, role)
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	updatedProduct, err := h.Service.UpdateProduct(id, &body, sub, role, version)
 	if err != nil {
 		return err
 	}
@@ -158,9 +185,16 @@ func (h *ProductHandler) UpdateProduct(c fiber.Ctx) error {
 
 func (h *ProductHandler) DeleteProduct(c fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
-	deleted, err := h.Service.DeleteProduct(id, role, sub)
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+	deleted, err := h.Service.DeleteProduct(id, role, sub, version)
 	if err != nil {
 		return err
 	}
@@ -171,7 +205,15 @@ func (h *ProductHandler) DeleteProduct(c fiber.Ctx) error {
 
 func (h *ProductHandler) RestoreProduct(c fiber.Ctx) error {
 	id := c.Params("id")
-	restored, err := h.Service.RestoreProduct(id)
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+	restored, err := h.Service.RestoreProduct(id, sub, version)
 	if err != nil {
 		return err
 	}
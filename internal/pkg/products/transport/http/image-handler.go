@@ -0,0 +1,104 @@
+package http
+
+import (
+	"io"
+
+	"github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func (h *ProductHandler) GetProductImages(c fiber.Ctx) error {
+	id := c.Params("id")
+	images, err := h.ImagesService.ListImages(id)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		images, fiber.StatusOK,
+	))
+}
+
+// UploadProductImage expects a multipart form with the file in the "image" field
+func (h *ProductHandler) UploadProductImage(c fiber.Ctx) error {
+	id := c.Params("id")
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		return http.ErrInvalidBody
+	}
+
+	maxSize := h.ImagesService.MaxSize()
+	if file.Size > int64(maxSize) {
+		return domain.ErrImageTooLarge
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return http.ErrInvalidBody
+	}
+	defer f.Close()
+
+	// the header size is sent by the client, the limit reader keeps the real read bounded too
+	data, err := io.ReadAll(io.LimitReader(f, int64(maxSize)+1))
+	if err != nil {
+		return http.ErrInvalidBody
+	}
+
+	image, err := h.ImagesService.UploadImage(id, data, sub, role)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(utils.Success(
+		image, fiber.StatusCreated,
+	))
+}
+
+func (h *ProductHandler) ReorderProductImages(c fiber.Ctx) error {
+	id := c.Params("id")
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	var body dtos.ReorderImagesDTO
+	if err := c.Bind().Body(&body); err != nil {
+		err := http.ErrInvalidBody
+		return err
+	}
+
+	images, err := h.ImagesService.ReorderImages(id, body.ImageIDs, sub, role)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		images, fiber.StatusOK,
+	))
+}
+
+func (h *ProductHandler) DeleteProductImage(c fiber.Ctx) error {
+	id := c.Params("id")
+	imageID := c.Params("imageId")
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := h.ImagesService.DeleteImage(id, imageID, sub, role)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		deleted, fiber.StatusOK,
+	))
+}
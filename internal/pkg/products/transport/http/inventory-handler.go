@@ -0,0 +1,52 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func (h *ProductHandler) GetStockHistory(c fiber.Ctx) error {
+	id := c.Params("id")
+
+	var query dtos.StockHistoryQueryDto
+	if err := c.Bind().Query(&query); err != nil {
+		err := http.ErrInvalidQuery
+		return err
+	}
+
+	// Apply defaults
+	query.ApplyDefaults()
+
+	history, err := h.Service.GetStockHistory(id, &query)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		history, fiber.StatusOK,
+	))
+}
+
+func (h *ProductHandler) AdjustStock(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	var body dtos.AdjustStockDTO
+	if err := c.Bind().Body(&body); err != nil {
+		err := http.ErrInvalidBody
+		return err
+	}
+
+	movement, err := h.Service.AdjustStock(id, &body, sub)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(utils.Success(
+		movement, fiber.StatusCreated,
+	))
+}
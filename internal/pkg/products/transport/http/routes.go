@@ -2,21 +2,25 @@ package http
 
 import (
 	"github.com/QuangNV23062004/learning-go/internal/middlewares"
-	"github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
 	"github.com/QuangNV23062004/learning-go/internal/utils"
 
 	"github.com/gofiber/fiber/v3"
 )
 
 type Router struct {
-	handler    *ProductHandler
-	jwtService *utils.JwtService
+	handler       *ProductHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
 }
 
-func NewRouter(handler *ProductHandler, jwtService *utils.JwtService) *Router {
+func NewRouter(handler *ProductHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
 	return &Router{
-		handler:    handler,
-		jwtService: jwtService,
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
 	}
 }
 
@@ -25,48 +29,83 @@ func (r *Router) SetupRoutes(app fiber.Router) {
 
 	product.Get("/",
 		middlewares.MarkPublic(),
-		middlewares.AuthMiddleware(r.jwtService),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
 		r.handler.GetPaginatedProducts)
 
+	product.Get("/search",
+		middlewares.MarkPublic(),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		r.handler.SearchProducts)
+
 	product.Get("/user/:id",
 		middlewares.MarkPublic(),
-		middlewares.AuthMiddleware(r.jwtService),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
 		r.handler.GetProductsByUserID)
 
 	product.Get("/user/:id/paginated",
 		middlewares.MarkPublic(),
-		middlewares.AuthMiddleware(r.jwtService),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
 		r.handler.GetPaginatedProductsByUserID)
 
 	product.Get("/all",
 		middlewares.MarkPublic(),
-		middlewares.AuthMiddleware(r.jwtService),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
 		r.handler.GetAllProducts)
 
 	product.Get("/:id",
 		middlewares.MarkPublic(),
-		middlewares.AuthMiddleware(r.jwtService),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
 		r.handler.GetProductByID)
 
-	product.Use(middlewares.AuthMiddleware(r.jwtService))
+	product.Get("/:id/images",
+		middlewares.MarkPublic(),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		r.handler.GetProductImages)
+
+	product.Get("/:id/variants",
+		middlewares.MarkPublic(),
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		r.handler.GetProductVariants)
+
+	product.Use(middlewares.AuthMiddleware(r.jwtService, r.tokenVersions))
 
 	product.Post("/",
-		middlewares.RoleMiddleware(
-			[]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsCreate)),
 		r.handler.CreateProduct)
 
 	product.Patch("/:id",
-		middlewares.RoleMiddleware(
-			[]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsUpdateOwn), string(enums.ProductsUpdateAny)),
 		r.handler.UpdateProduct)
 
 	product.Delete("/:id",
-		middlewares.RoleMiddleware(
-			[]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsDeleteOwn), string(enums.ProductsDeleteAny)),
 		r.handler.DeleteProduct)
 
 	product.Post("/:id/restore",
-		middlewares.RoleMiddleware(
-			[]string{string(enums.Admin)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsRestore)),
 		r.handler.RestoreProduct)
+
+	product.Put("/:id/variants",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsUpdateOwn), string(enums.ProductsUpdateAny)),
+		r.handler.ReplaceProductVariants)
+
+	product.Get("/:id/stock/movements",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.InventoryRead)),
+		r.handler.GetStockHistory)
+
+	product.Post("/:id/stock/adjustments",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.InventoryAdjust)),
+		r.handler.AdjustStock)
+
+	product.Post("/:id/images",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsUpdateOwn), string(enums.ProductsUpdateAny)),
+		r.handler.UploadProductImage)
+
+	product.Put("/:id/images/order",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsUpdateOwn), string(enums.ProductsUpdateAny)),
+		r.handler.ReorderProductImages)
+
+	product.Delete("/:id/images/:imageId",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.ProductsUpdateOwn), string(enums.ProductsUpdateAny)),
+		r.handler.DeleteProductImage)
 }
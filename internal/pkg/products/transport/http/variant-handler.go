@@ -0,0 +1,48 @@
+package http
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/http"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/dtos"
+	"github.com/QuangNV23062004/learning-go/internal/utils"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func (h *ProductHandler) GetProductVariants(c fiber.Ctx) error {
+	id := c.Params("id")
+	variants, err := h.Service.GetVariants(id)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		variants, fiber.StatusOK,
+	))
+}
+
+func (h *ProductHandler) ReplaceProductVariants(c fiber.Ctx) error {
+	id := c.Params("id")
+	role := http.Role(c)
+	sub, err := http.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	var body dtos.ReplaceVariantsDTO
+	if err := c.Bind().Body(&body); err != nil {
+		err := http.ErrInvalidBody
+		return err
+	}
+
+	version, err := http.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	variants, err := h.Service.ReplaceVariants(id, &body, sub, role, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		variants, fiber.StatusOK,
+	))
+}
@@ -0,0 +1,16 @@
+package types
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+)
+
+type ProductSearchResult struct {
+	// Core product fields
+	domain.Product
+
+	// Relevance of the match, higher is better
+	Rank float64 `json:"rank" gorm:"column:rank"`
+
+	// Product name as escaped html, the matched words are wrapped in <mark>. The typo tolerant fallback marks nothing
+	Snippet string `json:"snippet" gorm:"column:snippet"`
+}
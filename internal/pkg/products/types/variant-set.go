@@ -0,0 +1,13 @@
+package types
+
+import (
+	"github.com/QuangNV23062004/learning-go/internal/pkg/products/domain"
+)
+
+type VariantSet struct {
+	// Version of the product, send it back as If-Match when replacing the set
+	Version int `json:"version"`
+
+	Options  []*domain.ProductOption  `json:"options"`
+	Variants []*domain.ProductVariant `json:"variants"`
+}
@@ -2,12 +2,24 @@ package application
 
 import (
 	"errors"
-	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/QuangNV23062004/learning-go/internal/audit"
 	"github.com/QuangNV23062004/learning-go/internal/config"
+	baseDomain "github.com/QuangNV23062004/learning-go/internal/domain"
 	httpError "github.com/QuangNV23062004/learning-go/internal/http"
+	baseInfrastructure "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	cartInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/carts/infrastructure"
+	orderEnums "github.com/QuangNV23062004/learning-go/internal/pkg/orders/enums"
+	orderInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/orders/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionEnums "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
+	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/dtos"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
@@ -15,39 +27,76 @@ import (
 	"github.com/QuangNV23062004/learning-go/internal/types"
 	"github.com/QuangNV23062004/learning-go/internal/utils"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type UserService struct {
 	repo            *infrastructure.UserRepository
+	sessionRepo     *infrastructure.SessionRepository
+	pendingUserRepo *infrastructure.PendingUserRepository
+	userTokenRepo   *infrastructure.UserTokenRepository
+	throttleRepo    *infrastructure.LoginThrottleRepository
+	recoveryRepo    *infrastructure.RecoveryCodeRepository
+	permissions     *permissionApplication.PermissionService
+	orderRepo       *orderInfrastructure.OrderRepository
+	productRepo     *productInfrastructure.ProductRepository
+	cartRepo        *cartInfrastructure.CartRepository
+	auditRepo       *auditInfrastructure.AuditRepository
 	jwtService      *utils.JwtService
 	emailService    *utils.EmailService
 	passwordService *utils.PasswordService
 	serverConfig    *config.ServerConfig
+	authConfig      *config.AuthConfig
 }
 
 type UserCredentials struct {
 	AccessToken  string
 	RefreshToken string
 	User         *domain.User
+	// set instead of the tokens when the login still needs a 2fa code
+	MfaRequired bool
+	MfaToken    string
 }
 
+// EmailChangePreview is what confirming an email change link would do, shown before the token is used
+type EmailChangePreview struct {
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+const recoveryCodeCount = 10
+
 // Constructor liked
-func NewUserService(repo *infrastructure.UserRepository, JwtService *utils.JwtService, EmailService *utils.EmailService, PasswordService *utils.PasswordService, serverConfig *config.ServerConfig) *UserService {
+func NewUserService(repo *infrastructure.UserRepository, sessionRepo *infrastructure.SessionRepository, pendingUserRepo *infrastructure.PendingUserRepository, userTokenRepo *infrastructure.UserTokenRepository, throttleRepo *infrastructure.LoginThrottleRepository, recoveryRepo *infrastructure.RecoveryCodeRepository, permissions *permissionApplication.PermissionService, orderRepo *orderInfrastructure.OrderRepository, productRepo *productInfrastructure.ProductRepository, cartRepo *cartInfrastructure.CartRepository, auditRepo *auditInfrastructure.AuditRepository, JwtService *utils.JwtService, EmailService *utils.EmailService, PasswordService *utils.PasswordService, serverConfig *config.ServerConfig, authConfig *config.AuthConfig) *UserService {
 	return &UserService{
 		repo:            repo,
+		sessionRepo:     sessionRepo,
+		pendingUserRepo: pendingUserRepo,
+		userTokenRepo:   userTokenRepo,
+		throttleRepo:    throttleRepo,
+		recoveryRepo:    recoveryRepo,
+		permissions:     permissions,
+		orderRepo:       orderRepo,
+		productRepo:     productRepo,
+		cartRepo:        cartRepo,
+		auditRepo:       auditRepo,
 		jwtService:      JwtService,
 		emailService:    EmailService,
 		passwordService: PasswordService,
 
 		serverConfig: serverConfig,
+		authConfig:   authConfig,
 	}
 }
 
 // Auth Functions
 func (s *UserService) Register(registerDto dtos.RegisterDto) (*domain.User, error) {
-	// var user = domain.User{}
-
 	checkUser, err := s.repo.FindByEmail(registerDto.Email, nil)
 	// If no error, it means user was found - email already exists
 	if err == nil && checkUser != nil {
@@ -60,48 +109,78 @@ func (s *UserService) Register(registerDto dtos.RegisterDto) (*domain.User, erro
 		return nil, err
 	}
 
-	// hashedPassword, err := s.passwordService.HashPassword(registerDto.Password)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	user := &domain.User{
-		Email:     registerDto.Email,
-		Password:  registerDto.Password,
-		Username:  registerDto.Username,
-		Birthdate: registerDto.Birthdate,
-	}
-
-	verifyToken, verifyExpiry, err := s.jwtService.GenerateVerifyToken(user)
-
+	hashedPassword, err := s.passwordService.HashPassword(registerDto.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	verifyLink := s.serverConfig.Host + "/auth/verify?token=" + verifyToken
+	var pendingUser *domain.PendingUser
 
-	duration, err := time.ParseDuration(verifyExpiry)
-	if err != nil {
-		return nil, err
-	}
-	expiresAt := time.Now().Add(duration)
+	db := s.repo.GetDatabase(nil)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := s.pendingUserRepo.DeleteExpiredByEmail(registerDto.Email, now, tx); err != nil {
+			return err
+		}
 
-	// Prepare template data
-	templateData := map[string]interface{}{
-		"AppName":    s.serverConfig.AppName,
-		"Username":   registerDto.Username,
-		"VerifyLink": verifyLink,
-		"ExpiryTime": expiresAt.Format(time.RFC1123),
-	}
+		// a registration that has not expired is kept as it is and only its link is sent again,
+		// so nobody can replace another person's signup before it is confirmed
+		pendingUser, err = s.pendingUserRepo.FindLiveByEmail(registerDto.Email, now, tx)
+		if err != nil {
+			return err
+		}
 
-	// Render the email template
-	emailBody, err := s.emailService.RenderEmailTemplate("verify_email.html", templateData)
-	if err != nil {
-		log.Println("Render email failed:", err)
-		return nil, err
-	}
+		pendingID := uuid.NewString()
+		if pendingUser != nil {
+			pendingID = pendingUser.ID
+		}
+
+		// the token only carries the pending record id
+		verifyToken, verifyExpiry, err := s.jwtService.GenerateVerifyToken(pendingID)
+		if err != nil {
+			return err
+		}
+
+		if pendingUser == nil {
+			duration, err := time.ParseDuration(verifyExpiry)
+			if err != nil {
+				return err
+			}
+
+			pendingUser, err = s.pendingUserRepo.Create(&domain.PendingUser{
+				Email:      registerDto.Email,
+				Password:   hashedPassword,
+				Username:   registerDto.Username,
+				Birthdate:  registerDto.Birthdate,
+				ExpiresAt:  now.Add(duration),
+				BaseEntity: baseDomain.BaseEntity{ID: pendingID},
+			}, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		verifyLink := s.serverConfig.Host + "/auth/verify?token=" + verifyToken
+
+		// Prepare template data
+		templateData := map[string]interface{}{
+			"AppName":    s.serverConfig.AppName,
+			"Username":   pendingUser.Username,
+			"VerifyLink": verifyLink,
+			"ExpiryTime": pendingUser.ExpiresAt.Format(time.RFC1123),
+		}
+
+		// Render the email template
+		emailBody, err := s.emailService.RenderEmailTemplate("verify_email.html", templateData)
+		if err != nil {
+			log.Println("Render email failed:", err)
+			return err
+		}
+
+		// sent last so a failed email does not leave a pending record behind
+		return s.emailService.SendEmail(registerDto.Email, "Email Verification", emailBody)
+	})
 
-	err = s.emailService.SendEmail(registerDto.Email, "Email Verification", emailBody)
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +190,7 @@ func (s *UserService) Register(registerDto dtos.RegisterDto) (*domain.User, erro
 	return &domain.User{Email: registerDto.Email, Username: registerDto.Username, Birthdate: registerDto.Birthdate}, nil
 }
 
+// VerifyEmail turns the pending registration referenced by the token into a user
 func (s *UserService) VerifyEmail(tokenString string) (*domain.User, error) {
 
 	var createdUser *domain.User
@@ -119,30 +199,25 @@ func (s *UserService) VerifyEmail(tokenString string) (*domain.User, error) {
 	err := db.Transaction(func(tx *gorm.DB) error {
 
 		verifyVerificationClaims, err := s.jwtService.VerifyVerificationToken(tokenString)
-		if verifyVerificationClaims == (utils.VerifyEmailClaims{}) {
+		if err != nil || verifyVerificationClaims.PendingID == "" {
 			return domain.ErrInvalidVerificationToken
 		}
+
+		pendingUser, err := s.pendingUserRepo.FindByID(verifyVerificationClaims.PendingID, false, tx)
 		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidVerificationToken
+			}
 			return err
 		}
 
-		// fmt.Println("Token verified successfully,", verifyVerificationClaims)
-
-		var email, password, username, birthdate, role string
-
-		email = verifyVerificationClaims.Email
-		password = verifyVerificationClaims.Password
-		username = verifyVerificationClaims.Username
-		birthdate = verifyVerificationClaims.Birthdate
-
-		hashedPassword, err := s.passwordService.HashPassword(password)
-		if err != nil {
-			return err
+		if pendingUser.ExpiresAt.Before(time.Now()) {
+			return domain.ErrInvalidVerificationToken
 		}
 
-		role = string(enums.User)
+		role := string(enums.User)
 
-		checkUser, err := s.repo.FindByEmail(email, nil)
+		checkUser, err := s.repo.FindByEmail(pendingUser.Email, tx)
 		// If no error, it means user was found - email already exists
 		if err == nil && checkUser != nil {
 			return domain.ErrUserAlreadyExists
@@ -154,7 +229,7 @@ func (s *UserService) VerifyEmail(tokenString string) (*domain.User, error) {
 			return err
 		}
 
-		users, err := s.repo.FindAll(false, nil)
+		users, err := s.repo.FindAll(false, tx)
 		if err != nil {
 			return err
 		}
@@ -164,18 +239,23 @@ func (s *UserService) VerifyEmail(tokenString string) (*domain.User, error) {
 		}
 
 		newUser := &domain.User{
-			Email:     email,
-			Password:  hashedPassword,
-			Username:  username,
-			Birthdate: birthdate,
+			Email:     pendingUser.Email,
+			Password:  pendingUser.Password,
+			Username:  pendingUser.Username,
+			Birthdate: pendingUser.Birthdate,
 			Role:      role,
 		}
 
-		createdUser, err = s.repo.Create(newUser, nil)
+		createdUser, err = s.repo.Create(newUser, tx)
 		if err != nil {
 			return err
 		}
 
+		// the link is single use
+		if _, err := s.pendingUserRepo.HardDelete(pendingUser.ID, tx); err != nil {
+			return err
+		}
+
 		return nil
 	})
 
@@ -188,23 +268,140 @@ func (s *UserService) VerifyEmail(tokenString string) (*domain.User, error) {
 	return createdUser, nil
 }
 
-func (s *UserService) Login(loginDto dtos.LoginDto) (*UserCredentials, error) {
-	user, err := s.repo.FindByEmail(loginDto.Email, nil)
+// normalizeEmail is the form emails are compared and throttled in
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Login checks the credentials, failed attempts are counted per account and per client ip
+func (s *UserService) Login(loginDto dtos.LoginDto, ip string) (*UserCredentials, error) {
+	// the throttle key and the lookup use the same form of the email
+	account := normalizeEmail(loginDto.Email)
+
+	lockedUntil, err := s.throttleRepo.LockedUntil(string(enums.ThrottleIP), ip, nil)
 	if err != nil {
-		return nil, domain.ErrInvalidCredentials
+		return nil, err
+	}
+	if lockedUntil != nil {
+		return nil, domain.ErrTooManyAttempts
+	}
+
+	lockedUntil, err = s.throttleRepo.LockedUntil(string(enums.ThrottleAccount), account, nil)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		return nil, domain.ErrAccountLocked
+	}
+
+	user, err := s.repo.FindByEmail(account, nil)
+	if err == nil {
+		err = s.passwordService.CompareHashAndPassword([]byte(user.Password), []byte(loginDto.Password))
 	}
 
-	err = s.passwordService.CompareHashAndPassword([]byte(user.Password), []byte(loginDto.Password))
 	if err != nil {
+		// unknown emails are counted too so they cannot be told apart
+		if err := s.recordLoginFailure(enums.ThrottleAccount, account, s.authConfig.MaxLoginAttempts); err != nil {
+			return nil, err
+		}
+		if err := s.recordLoginFailure(enums.ThrottleIP, ip, s.authConfig.MaxIPLoginAttempts); err != nil {
+			return nil, err
+		}
 		return nil, domain.ErrInvalidCredentials
 	}
 
+	// the password is right, so the account goes back to the normal schedule even when a second
+	// factor follows. The ip count is left alone so one valid account cannot clear it
+	if _, err := s.throttleRepo.Reset(string(enums.ThrottleAccount), account, nil); err != nil {
+		return nil, err
+	}
+
+	if user.Suspended {
+		return nil, domain.ErrAccountSuspended
+	}
+
+	if user.TwoFactorEnabled {
+		mfaToken, err := s.jwtService.GenerateMfaToken(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &UserCredentials{MfaRequired: true, MfaToken: mfaToken}, nil
+	}
+
+	// a login starts a new session family
+	return s.issueCredentials(user, "", uuid.NewString(), nil)
+}
+
+// recordLoginFailure locks the subject once maxAttempts is reached, every further failure doubles the lockout
+func (s *UserService) recordLoginFailure(kind enums.ThrottleKind, subject string, maxAttempts int) error {
+	window, err := time.ParseDuration(s.authConfig.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	throttle, err := s.throttleRepo.RecordFailure(string(kind), subject, window, nil)
+	if err != nil {
+		return err
+	}
+
+	if throttle.FailedCount < maxAttempts {
+		return nil
+	}
+
+	base, err := time.ParseDuration(s.authConfig.LockoutBase)
+	if err != nil {
+		return err
+	}
+
+	maxLockout, err := time.ParseDuration(s.authConfig.LockoutMax)
+	if err != nil {
+		return err
+	}
+
+	lockout := base
+	for i := maxAttempts; i < throttle.FailedCount && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > maxLockout {
+		lockout = maxLockout
+	}
+
+	return s.throttleRepo.Lock(throttle.ID, time.Now().Add(lockout), nil)
+}
+
+// UnlockUser clears the failed logins and lockout of the account, admin only
+func (s *UserService) UnlockUser(id string) (bool, error) {
+	user, err := s.repo.FindByID(id, true, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return s.throttleRepo.Reset(string(enums.ThrottleAccount), normalizeEmail(user.Email), nil)
+}
+
+// issueCredentials signs a token pair and stores the refresh token as session tokenID of the given family,
+// an empty familyID starts a new one
+func (s *UserService) issueCredentials(user *domain.User, familyID string, tokenID string, tx *gorm.DB) (*UserCredentials, error) {
+	if familyID == "" {
+		familyID = tokenID
+	}
+
 	accessToken, err := s.jwtService.GenerateAccessToken(user)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user)
+	refreshToken, expiresAt, err := s.jwtService.GenerateRefreshToken(user, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.sessionRepo.Create(&domain.Session{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenID:   tokenID,
+		ExpiresAt: expiresAt,
+	}, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -237,7 +434,7 @@ func (s *UserService) GetAllUsers(includeDeleted bool) ([]domain.User, error) {
 // shared between admin and user
 func (s *UserService) GetUserByID(id string, role string, includeDeleted bool) (*domain.User, error) {
 	safeIncludeDeleted := false
-	if role == string(enums.Admin) {
+	if s.permissions.HasPermission(role, permissionEnums.UsersReadDeleted) {
 		safeIncludeDeleted = includeDeleted
 	}
 
@@ -251,13 +448,13 @@ func (s *UserService) GetUserByID(id string, role string, includeDeleted bool) (
 }
 
 // only admin can restore users
-func (s *UserService) RestoreUser(id string) (bool, error) {
+func (s *UserService) RestoreUser(id string, sub string, version int) (bool, error) {
 	var restored bool
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 
 	err := db.Transaction(func(tx *gorm.DB) error {
 
-		user, err := s.repo.FindByID(id, false, nil)
+		user, err := s.repo.FindByID(id, true, tx)
 		if user == nil {
 			return domain.ErrUserNotFound
 		}
@@ -266,7 +463,11 @@ func (s *UserService) RestoreUser(id string) (bool, error) {
 			return err
 		}
 
-		restored, err = s.repo.Restore(id, nil)
+		if !user.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		restored, err = s.repo.Restore(id, tx)
 		if err != nil {
 			return err
 		}
@@ -280,17 +481,17 @@ func (s *UserService) RestoreUser(id string) (bool, error) {
 	return restored, nil
 }
 
-func (s *UserService) DeleteUser(id string, role string, sub string) (bool, error) {
+func (s *UserService) DeleteUser(id string, role string, sub string, version int) (bool, error) {
 
 	var deleted bool
 
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 	err := db.Transaction(func(tx *gorm.DB) error {
-		if role != string(enums.Admin) && id != sub {
+		if id != sub && !s.permissions.HasPermission(role, permissionEnums.UsersDeleteAny) {
 			return httpError.ErrForbidden
 		}
 
-		user, err := s.repo.FindByID(id, false, nil)
+		user, err := s.repo.FindByID(id, false, tx)
 		if user == nil {
 			return domain.ErrUserNotFound
 		}
@@ -299,8 +500,12 @@ func (s *UserService) DeleteUser(id string, role string, sub string) (bool, erro
 			return err
 		}
 
+		if !user.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
 		var deleteErr error
-		deleted, deleteErr = s.repo.Delete(id, nil)
+		deleted, deleteErr = s.repo.Delete(id, tx)
 		if deleteErr != nil {
 			return deleteErr
 		}
@@ -314,20 +519,25 @@ func (s *UserService) DeleteUser(id string, role string, sub string) (bool, erro
 	return deleted, nil
 }
 
-func (s *UserService) UpdateUser(id string, userDto dtos.UpdateUserDto, sub string) (*domain.User, error) {
+func (s *UserService) UpdateUser(id string, userDto dtos.UpdateUserDto, sub string, version int) (*domain.User, error) {
 
 	var user *domain.User
 
-	db := s.repo.GetDatabase(nil)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
 	err := db.Transaction(func(tx *gorm.DB) error {
 		if id != sub {
 			return httpError.ErrForbidden
 		}
 
-		checkUser, err := s.repo.FindByID(id, false, nil)
+		checkUser, err := s.repo.FindByID(id, false, tx)
 		if err != nil {
 			return err
 		}
+
+		if !checkUser.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
 		if userDto.Username != "" {
 			checkUser.Username = userDto.Username
 		}
@@ -336,7 +546,7 @@ func (s *UserService) UpdateUser(id string, userDto dtos.UpdateUserDto, sub stri
 			checkUser.Birthdate = userDto.Birthdate
 		}
 
-		user, err = s.repo.Update(checkUser, nil)
+		user, err = s.repo.Update(checkUser, tx)
 
 		if err != nil {
 			return err
@@ -353,16 +563,8 @@ func (s *UserService) UpdateUser(id string, userDto dtos.UpdateUserDto, sub stri
 }
 
 // admin only
-func (s *UserService) PaginatedUsers(page int, limit int, search string, searchField string, order string, sortBy string, includeDeleted bool) (*types.Paginated[domain.User], error) {
-	allowed := map[string]bool{"email": true, "username": true, "created_at": true}
-	if !allowed[searchField] {
-		searchField = "email"
-	}
-	if !allowed[sortBy] {
-		sortBy = "created_at"
-	}
-
-	data, err := s.repo.Paginated(page, limit, search, searchField, order, sortBy, includeDeleted, nil)
+func (s *UserService) PaginatedUsers(page int, limit int, cursor string, search string, searchField string, order string, sortBy string, filters []types.Filter, includeDeleted bool) (*types.Paginated[domain.User], error) {
+	data, err := s.repo.Paginated(page, limit, cursor, search, searchField, order, sortBy, filters, includeDeleted, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -385,48 +587,1000 @@ func (s *UserService) PaginatedUsers(page int, limit int, search string, searchF
 		SortBy:      data.SortBy,
 		HasPrevious: data.HasPrevious,
 		HasNext:     data.HasNext,
+		NextCursor:  data.NextCursor,
 	}, nil
 }
 
+// Logout revokes the session family of the refresh token, an invalid token has nothing left to revoke
+func (s *UserService) Logout(refreshTokenString string) error {
+	if refreshTokenString == "" {
+		return nil
+	}
+
+	claims, err := s.jwtService.VerifyRefreshToken(refreshTokenString)
+	if err != nil || claims == nil {
+		return nil
+	}
+
+	tokenID, _ := claims["jti"].(string)
+	if tokenID == "" {
+		return nil
+	}
+
+	session, err := s.sessionRepo.FindByTokenID(tokenID, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sessionRepo.RevokeFamily(session.FamilyID, nil)
+}
+
+// LogoutAll revokes every session of the user and invalidates all access tokens issued so far
+func (s *UserService) LogoutAll(sub string) error {
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	return db.Transaction(func(tx *gorm.DB) error {
+		return s.revokeAllSessions(sub, tx)
+	})
+}
+
+func (s *UserService) revokeAllSessions(userID string, tx *gorm.DB) error {
+	if err := s.repo.IncrementTokenVersion(userID, tx); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeAllForUser(userID, tx)
+}
+
+// RefreshTokens rotates the refresh token on every call. Presenting a token that was already rotated
+// means it leaked, so the whole session family is revoked
 func (s *UserService) RefreshTokens(refreshTokenString string) (*UserCredentials, error) {
 	claims, err := s.jwtService.VerifyRefreshToken(refreshTokenString)
+	if err != nil || claims == nil {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	sub, _ := claims["sub"].(string)
+	tokenID, _ := claims["jti"].(string)
+	if sub == "" || tokenID == "" {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	var credentials *UserCredentials
+	var reusedFamily string
+
+	db := s.repo.GetDatabase(nil)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		session, err := s.sessionRepo.FindByTokenID(tokenID, tx)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if session.UserID != sub || session.ExpiresAt.Before(time.Now()) {
+			return domain.ErrInvalidRefreshToken
+		}
+
+		if session.RevokedAt != nil {
+			reusedFamily = session.FamilyID
+			return nil
+		}
+
+		checkUser, err := s.repo.FindByID(sub, false, tx)
+		if checkUser == nil {
+			return domain.ErrUserNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if checkUser.Suspended {
+			return domain.ErrAccountSuspended
+		}
+
+		nextTokenID := uuid.NewString()
+		rotated, err := s.sessionRepo.Revoke(tokenID, &nextTokenID, tx)
+		if err != nil {
+			return err
+		}
+
+		// a concurrent refresh used the token first
+		if !rotated {
+			reusedFamily = session.FamilyID
+			return nil
+		}
+
+		credentials, err = s.issueCredentials(checkUser, session.FamilyID, nextTokenID, tx)
+		return err
+	})
+
 	if err != nil {
 		return nil, err
 	}
 
-	var sub, exp string
+	// revoked outside the transaction above so it is not rolled back with the error
+	if reusedFamily != "" {
+		if err := s.sessionRepo.RevokeFamily(reusedFamily, nil); err != nil {
+			return nil, err
+		}
+		return nil, domain.ErrRefreshTokenReused
+	}
+
+	return credentials, nil
+}
+
+// ForgotPassword emails a single use reset link. It never reveals whether the email is registered
+func (s *UserService) ForgotPassword(forgotDto dtos.ForgotPasswordDto) error {
+	user, err := s.repo.FindByEmail(forgotDto.Email, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// failures past this point are only logged, answering differently would leak which emails exist
+	if err := s.sendPasswordReset(user); err != nil {
+		log.Println("Password reset failed for user", user.ID, ":", err)
+	}
 
-	sub = claims["sub"].(string)
-	exp = fmt.Sprintf("%v", claims["exp"])
+	return nil
+}
 
-	checkUser, err := s.repo.FindByID(sub, false, nil)
-	if checkUser == nil {
-		return nil, gorm.ErrRecordNotFound
+// sendPasswordReset replaces the user's reset token and mails the link to it
+func (s *UserService) sendPasswordReset(user *domain.User) error {
+	duration, err := time.ParseDuration(s.authConfig.ResetExpiry)
+	if err != nil {
+		return err
 	}
 
+	token, tokenHash, err := utils.GenerateOpaqueToken()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var accessToken, refreshToken string
+	db := s.repo.GetDatabase(nil)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userTokenRepo.InvalidateForUser(user.ID, string(enums.PasswordReset), tx); err != nil {
+			return err
+		}
+
+		_, err = s.userTokenRepo.Create(&domain.UserToken{
+			UserID:    user.ID,
+			Purpose:   string(enums.PasswordReset),
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(duration),
+		}, tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	templateData := map[string]interface{}{
+		"AppName":    s.serverConfig.AppName,
+		"Username":   user.Username,
+		"ResetLink":  s.serverConfig.FrontendURL + "/reset-password?token=" + token,
+		"ExpiryTime": duration.String(),
+	}
+
+	emailBody, err := s.emailService.RenderEmailTemplate("reset_password.html", templateData)
+	if err != nil {
+		return err
+	}
+
+	return s.emailService.SendEmail(user.Email, "Password Reset", emailBody)
+}
+
+// ResetPassword consumes the reset token, sets the new password and signs the user out everywhere
+func (s *UserService) ResetPassword(resetDto dtos.ResetPasswordDto) error {
+	hashedPassword, err := s.passwordService.HashPassword(resetDto.Password)
+	if err != nil {
+		return err
+	}
+
+	db := s.repo.GetDatabase(nil)
+	return db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := s.userTokenRepo.FindUsable(utils.HashToken(resetDto.Token), string(enums.PasswordReset), tx)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidResetToken
+			}
+			return err
+		}
+
+		// nobody is signed in, the change is made by the owner of the token
+		tx = baseInfrastructure.WithActor(tx, userToken.UserID)
+
+		consumed, err := s.userTokenRepo.Consume(userToken.ID, tx)
+		if err != nil {
+			return err
+		}
+
+		// another request used the token first
+		if !consumed {
+			return domain.ErrInvalidResetToken
+		}
+
+		user, err := s.repo.FindByID(userToken.UserID, false, tx)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidResetToken
+			}
+			return err
+		}
+
+		user.Password = hashedPassword
+		if _, err := s.repo.Update(user, tx); err != nil {
+			return err
+		}
+
+		return s.revokeAllSessions(user.ID, tx)
+	})
+}
+
+// ChangePassword sets a new password after checking the current one. Every other session is signed out
+// and the caller gets a fresh token pair
+func (s *UserService) ChangePassword(id string, changeDto dtos.ChangePasswordDto, sub string) (*UserCredentials, error) {
+	if id != sub {
+		return nil, httpError.ErrForbidden
+	}
 
-	accessToken, err = s.jwtService.GenerateAccessToken(checkUser)
+	hashedPassword, err := s.passwordService.HashPassword(changeDto.NewPassword)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken = refreshTokenString
+	var credentials *UserCredentials
 
-	if time.Unix(int64(s.jwtService.ParseExp(exp)), 0).Sub(time.Now()) < 24*time.Hour {
-		refreshToken, err = s.jwtService.GenerateRefreshToken(checkUser)
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.repo.FindByID(id, false, tx)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		if err := s.passwordService.CompareHashAndPassword([]byte(user.Password), []byte(changeDto.CurrentPassword)); err != nil {
+			return domain.ErrIncorrectPassword
+		}
+
+		user.Password = hashedPassword
+		user, err = s.repo.Update(user, tx)
+		if err != nil {
+			return err
+		}
+
+		if err := s.revokeAllSessions(user.ID, tx); err != nil {
+			return err
+		}
+
+		// reload so the new access token carries the bumped token version
+		user, err = s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
 		}
+
+		credentials, err = s.issueCredentials(user, "", uuid.NewString(), tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return &UserCredentials{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		User:         checkUser,
-	}, nil
+	return credentials, nil
+}
+
+// RequestEmailChange sends a confirmation link to the new address, the email is only swapped once it is confirmed
+func (s *UserService) RequestEmailChange(id string, changeDto dtos.ChangeEmailDto, sub string) error {
+	if id != sub {
+		return httpError.ErrForbidden
+	}
+
+	user, err := s.repo.FindByID(id, false, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := s.passwordService.CompareHashAndPassword([]byte(user.Password), []byte(changeDto.Password)); err != nil {
+		return domain.ErrIncorrectPassword
+	}
+
+	email := normalizeEmail(changeDto.Email)
+	if email == normalizeEmail(user.Email) {
+		return domain.ErrSameEmail
+	}
+
+	taken, err := s.repo.EmailTaken(email, nil)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return domain.ErrEmailTaken
+	}
+
+	duration, err := time.ParseDuration(s.authConfig.EmailChangeExpiry)
+	if err != nil {
+		return err
+	}
+
+	token, tokenHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	db := s.repo.GetDatabase(nil)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userTokenRepo.InvalidateForUser(user.ID, string(enums.EmailChange), tx); err != nil {
+			return err
+		}
+
+		if _, err := s.userTokenRepo.Create(&domain.UserToken{
+			UserID:    user.ID,
+			Purpose:   string(enums.EmailChange),
+			TokenHash: tokenHash,
+			Payload:   email,
+			ExpiresAt: time.Now().Add(duration),
+		}, tx); err != nil {
+			return err
+		}
+
+		templateData := map[string]interface{}{
+			"AppName":     s.serverConfig.AppName,
+			"Username":    user.Username,
+			"ConfirmLink": s.serverConfig.FrontendURL + "/confirm-email?token=" + token,
+			"ExpiryTime":  duration.String(),
+		}
+
+		emailBody, err := s.emailService.RenderEmailTemplate("confirm_email_change.html", templateData)
+		if err != nil {
+			return err
+		}
+
+		// sent to the new address so only its owner can confirm it
+		return s.emailService.SendEmail(email, "Confirm Email Change", emailBody)
+	})
+}
+
+// PreviewEmailChange tells which email the token would switch to without using it, opening the link
+// only shows this so mail scanners and link prefetchers can't complete the change
+func (s *UserService) PreviewEmailChange(token string) (*EmailChangePreview, error) {
+	userToken, err := s.userTokenRepo.FindUsable(utils.HashToken(token), string(enums.EmailChange), nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	return &EmailChangePreview{Email: userToken.Payload, ExpiresAt: userToken.ExpiresAt}, nil
+}
+
+// ConfirmEmailChange swaps in the email carried by the token if nobody claimed it in the meantime
+func (s *UserService) ConfirmEmailChange(token string) (*domain.User, error) {
+	var user *domain.User
+
+	db := s.repo.GetDatabase(nil)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		userToken, err := s.userTokenRepo.FindUsable(utils.HashToken(token), string(enums.EmailChange), tx)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidEmailChangeToken
+			}
+			return err
+		}
+
+		tx = baseInfrastructure.WithActor(tx, userToken.UserID)
+
+		consumed, err := s.userTokenRepo.Consume(userToken.ID, tx)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			return domain.ErrInvalidEmailChangeToken
+		}
+
+		// checked again since the email may have been registered after the link was sent
+		taken, err := s.repo.EmailTaken(userToken.Payload, tx)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return domain.ErrEmailTaken
+		}
+
+		checkUser, err := s.repo.FindByID(userToken.UserID, false, tx)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInvalidEmailChangeToken
+			}
+			return err
+		}
+
+		checkUser.Email = userToken.Payload
+		user, err = s.repo.Update(checkUser, tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+
+	return user, nil
+}
+
+// VerifyTwoFactor finishes a login started with an mfa pending token, failed codes count towards the lockout
+func (s *UserService) VerifyTwoFactor(verifyDto dtos.VerifyTwoFactorDto, ip string) (*UserCredentials, error) {
+	userID, err := s.jwtService.VerifyMfaToken(verifyDto.MfaToken)
+	if err != nil {
+		return nil, domain.ErrInvalidMfaToken
+	}
+
+	lockedUntil, err := s.throttleRepo.LockedUntil(string(enums.ThrottleIP), ip, nil)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		return nil, domain.ErrTooManyAttempts
+	}
+
+	user, err := s.repo.FindByID(userID, false, nil)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidMfaToken
+		}
+		return nil, err
+	}
+
+	account := normalizeEmail(user.Email)
+	lockedUntil, err = s.throttleRepo.LockedUntil(string(enums.ThrottleAccount), account, nil)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil != nil {
+		return nil, domain.ErrAccountLocked
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, domain.ErrInvalidMfaToken
+	}
+
+	if user.Suspended {
+		return nil, domain.ErrAccountSuspended
+	}
+
+	var credentials *UserCredentials
+
+	db := s.repo.GetDatabase(nil)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkTwoFactorCode(user, verifyDto.Code, tx); err != nil {
+			return err
+		}
+
+		credentials, err = s.issueCredentials(user, "", uuid.NewString(), tx)
+		return err
+	})
+
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		if err := s.recordLoginFailure(enums.ThrottleAccount, account, s.authConfig.MaxLoginAttempts); err != nil {
+			return nil, err
+		}
+		if err := s.recordLoginFailure(enums.ThrottleIP, ip, s.authConfig.MaxIPLoginAttempts); err != nil {
+			return nil, err
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.throttleRepo.Reset(string(enums.ThrottleAccount), account, nil); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// checkTwoFactorCode accepts a totp code whose step was not used yet or an unused recovery code
+func (s *UserService) checkTwoFactorCode(user *domain.User, code string, tx *gorm.DB) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now()); ok {
+		advanced, err := s.repo.AdvanceTwoFactorStep(user.ID, step, tx)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return domain.ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	consumed, err := s.recoveryRepo.Consume(user.ID, utils.HashToken(utils.NormalizeRecoveryCode(code)), tx)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return domain.ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// SetupTwoFactor stores a new secret for the user, it is not enforced until EnableTwoFactor confirms it
+func (s *UserService) SetupTwoFactor(id string, sub string) (*TwoFactorSetup, error) {
+	if id != sub {
+		return nil, httpError.ErrForbidden
+	}
+
+	var setup *TwoFactorSetup
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if user.TwoFactorEnabled {
+			return domain.ErrTwoFactorAlreadyEnabled
+		}
+
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return err
+		}
+
+		user.TwoFactorSecret = secret
+		if _, err := s.repo.Update(user, tx); err != nil {
+			return err
+		}
+
+		setup = &TwoFactorSetup{
+			Secret:          secret,
+			ProvisioningURI: utils.TOTPProvisioningURI(s.serverConfig.AppName, user.Email, secret),
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return setup, nil
+}
+
+// EnableTwoFactor turns 2fa on once the user proves the authenticator works, the recovery codes are only shown here
+func (s *UserService) EnableTwoFactor(id string, codeDto dtos.TwoFactorCodeDto, sub string) ([]string, error) {
+	if id != sub {
+		return nil, httpError.ErrForbidden
+	}
+
+	var codes []string
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if user.TwoFactorEnabled {
+			return domain.ErrTwoFactorAlreadyEnabled
+		}
+
+		if user.TwoFactorSecret == "" {
+			return domain.ErrTwoFactorNotSetUp
+		}
+
+		step, ok := utils.ValidateTOTP(user.TwoFactorSecret, strings.TrimSpace(codeDto.Code), time.Now())
+		if !ok {
+			return domain.ErrInvalidTwoFactorCode
+		}
+
+		user.TwoFactorEnabled = true
+		user.TwoFactorLastStep = step
+		if _, err := s.repo.Update(user, tx); err != nil {
+			return err
+		}
+
+		codes, err = s.replaceRecoveryCodes(user.ID, tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor needs the password and a current code so a stolen session alone cannot turn it off
+func (s *UserService) DisableTwoFactor(id string, disableDto dtos.DisableTwoFactorDto, sub string) error {
+	if id != sub {
+		return httpError.ErrForbidden
+	}
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	return db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if !user.TwoFactorEnabled {
+			return domain.ErrTwoFactorNotEnabled
+		}
+
+		if err := s.passwordService.CompareHashAndPassword([]byte(user.Password), []byte(disableDto.Password)); err != nil {
+			return domain.ErrIncorrectPassword
+		}
+
+		if err := s.checkTwoFactorCode(user, disableDto.Code, tx); err != nil {
+			return err
+		}
+
+		// reload, checking the code bumped the version
+		user, err = s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		user.TwoFactorEnabled = false
+		user.TwoFactorSecret = ""
+		user.TwoFactorLastStep = 0
+		if _, err := s.repo.Update(user, tx); err != nil {
+			return err
+		}
+
+		return s.recoveryRepo.DeleteForUser(user.ID, tx)
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, a current code is required
+func (s *UserService) RegenerateRecoveryCodes(id string, codeDto dtos.TwoFactorCodeDto, sub string) ([]string, error) {
+	if id != sub {
+		return nil, httpError.ErrForbidden
+	}
+
+	var codes []string
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if !user.TwoFactorEnabled {
+			return domain.ErrTwoFactorNotEnabled
+		}
+
+		if err := s.checkTwoFactorCode(user, codeDto.Code, tx); err != nil {
+			return err
+		}
+
+		codes, err = s.replaceRecoveryCodes(user.ID, tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *UserService) replaceRecoveryCodes(userID string, tx *gorm.DB) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	}
+
+	if err := s.recoveryRepo.ReplaceForUser(userID, hashes, tx); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// ChangeRole sets the user's role, the last active admin cannot be demoted
+func (s *UserService) ChangeRole(id string, roleDto dtos.ChangeRoleDto, sub string, version int) (*domain.User, error) {
+	role := enums.Role(roleDto.Role)
+	if role != enums.Admin && role != enums.User {
+		return nil, domain.ErrInvalidRole
+	}
+
+	var user *domain.User
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		checkUser, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if !checkUser.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		previous := checkUser.Role
+		if previous == string(role) {
+			user = checkUser
+			return nil
+		}
+
+		if role != enums.Admin {
+			if err := s.ensureNotLastAdmin(checkUser, tx); err != nil {
+				return err
+			}
+		}
+
+		checkUser.Role = string(role)
+		if _, err := s.repo.Update(checkUser, tx); err != nil {
+			return err
+		}
+
+		// the role is a token claim, make the user pick up a new one
+		if err := s.repo.IncrementTokenVersion(id, tx); err != nil {
+			return err
+		}
+
+		if err := s.auditRepo.Record(sub, audit.UserRoleChanged, "user", id, map[string]string{
+			"from": previous,
+			"to":   string(role),
+		}, tx); err != nil {
+			return err
+		}
+
+		user, err = s.repo.FindByID(id, false, tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// SuspendUser blocks sign in and revokes every session without touching the user's data
+func (s *UserService) SuspendUser(id string, suspendDto dtos.SuspendUserDto, sub string, version int) (*domain.User, error) {
+	var user *domain.User
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		checkUser, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if !checkUser.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		if checkUser.Suspended {
+			user = checkUser
+			return nil
+		}
+
+		if err := s.ensureNotLastAdmin(checkUser, tx); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		checkUser.Suspended = true
+		checkUser.SuspendedAt = &now
+		if _, err := s.repo.Update(checkUser, tx); err != nil {
+			return err
+		}
+
+		if err := s.revokeAllSessions(id, tx); err != nil {
+			return err
+		}
+
+		if err := s.auditRepo.Record(sub, audit.UserSuspended, "user", id, map[string]string{
+			"reason": suspendDto.Reason,
+		}, tx); err != nil {
+			return err
+		}
+
+		user, err = s.repo.FindByID(id, false, tx)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+func (s *UserService) ReactivateUser(id string, sub string, version int) (*domain.User, error) {
+	var user *domain.User
+
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		checkUser, err := s.repo.FindByID(id, false, tx)
+		if err != nil {
+			return err
+		}
+
+		if !checkUser.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		if !checkUser.Suspended {
+			user = checkUser
+			return nil
+		}
+
+		checkUser.Suspended = false
+		checkUser.SuspendedAt = nil
+		user, err = s.repo.Update(checkUser, tx)
+		if err != nil {
+			return err
+		}
+
+		return s.auditRepo.Record(sub, audit.UserReactivated, "user", id, nil, tx)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// PurgeUser permanently deletes the user with their products, orders and credentials.
+// Stock held by their open orders goes back to the products first. Sellers whose products are
+// on other customers' open orders are refused, those orders still need the products to restock
+func (s *UserService) PurgeUser(id string, sub string, version int) (bool, error) {
+	db := baseInfrastructure.WithActor(s.repo.GetDatabase(nil), sub)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.repo.FindByID(id, true, tx)
+		if err != nil {
+			return err
+		}
+
+		if !user.MatchesVersion(version) {
+			return baseDomain.ErrVersionConflict
+		}
+
+		if err := s.ensureNotLastAdmin(user, tx); err != nil {
+			return err
+		}
+
+		// same statuses the order service cancels on delete
+		items, err := s.orderRepo.FindItemsByUserIDAndStatus(id, []string{string(orderEnums.Pending), string(orderEnums.Paid)}, tx)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].ProductID != items[j].ProductID {
+				return items[i].ProductID < items[j].ProductID
+			}
+			return items[i].VariantID < items[j].VariantID
+		})
+
+		// products are locked before their variants, the order the order service takes them in.
+		// The user's own products are locked with them so no new order lands on them after the check below
+		ownProducts, err := s.productRepo.FindAllByUserID(id, true, tx)
+		if err != nil {
+			return err
+		}
+		productIDs := make([]string, 0, len(items)+len(ownProducts))
+		for _, product := range ownProducts {
+			productIDs = append(productIDs, product.ID)
+		}
+		for _, item := range items {
+			if !slices.Contains(productIDs, item.ProductID) {
+				productIDs = append(productIDs, item.ProductID)
+			}
+		}
+		if _, err := s.productRepo.FindByIDsForUpdate(productIDs, true, tx); err != nil {
+			return err
+		}
+
+		// other customers' orders still need the products to cancel, refund or expire against
+		openStatuses := make([]string, 0, len(orderEnums.Open))
+		for _, status := range orderEnums.Open {
+			openStatuses = append(openStatuses, string(status))
+		}
+		soldOpen, err := s.orderRepo.HasOrdersOnSellerProducts(id, openStatuses, tx)
+		if err != nil {
+			return err
+		}
+		if soldOpen {
+			return domain.ErrProductsOnOpenOrders
+		}
+
+		for _, item := range items {
+			// a product or variant that is already gone has nothing to give back to
+			if item.VariantID == "" {
+				continue
+			}
+			if _, err := s.productRepo.ReleaseStock(item.ProductID, item.VariantID, item.Quantity, item.OrderID, tx); err != nil {
+				return err
+			}
+		}
+
+		if err := s.cartRepo.DeleteForUser(id, tx); err != nil {
+			return err
+		}
+
+		orders, err := s.orderRepo.PurgeByUserID(id, tx)
+		if err != nil {
+			return err
+		}
+
+		products, err := s.productRepo.PurgeByUserID(id, tx)
+		if err != nil {
+			return err
+		}
+
+		if err := s.sessionRepo.DeleteForUser(id, tx); err != nil {
+			return err
+		}
+
+		if err := s.userTokenRepo.DeleteForUser(id, tx); err != nil {
+			return err
+		}
+
+		if err := s.recoveryRepo.DeleteForUser(id, tx); err != nil {
+			return err
+		}
+
+		if _, err := s.throttleRepo.Reset(string(enums.ThrottleAccount), normalizeEmail(user.Email), tx); err != nil {
+			return err
+		}
+
+		if _, err := s.repo.HardDelete(id, tx); err != nil {
+			return err
+		}
+
+		return s.auditRepo.Record(sub, audit.UserPurged, "user", id, map[string]interface{}{
+			"email":    user.Email,
+			"username": user.Username,
+			"orders":   orders,
+			"products": products,
+		}, tx)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ensureNotLastAdmin fails when the user is the only admin left who can sign in
+func (s *UserService) ensureNotLastAdmin(user *domain.User, tx *gorm.DB) error {
+	if user.Role != string(enums.Admin) || user.Suspended || user.IsDeleted {
+		return nil
+	}
+
+	admins, err := s.repo.LockActiveAdmins(tx)
+	if err != nil {
+		return err
+	}
+
+	if len(admins) <= 1 {
+		return domain.ErrLastAdmin
+	}
+	return nil
 }
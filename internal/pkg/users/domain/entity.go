@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/QuangNV23062004/learning-go/internal/domain"
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
 
 type User struct {
 	Email     string `json:"email" gorm:"uniqueIndex"`
@@ -8,9 +12,22 @@ type User struct {
 	Username  string `json:"username" gorm:"not null"`
 	Role      string `json:"role" gorm:"not null;default:'user'"`
 	Birthdate string `json:"birthdate" gorm:"not null"`
+	// bumped to invalidate every access token issued before
+	TokenVersion int `json:"-" gorm:"not null;default:0"`
+	// the secret is stored at setup, 2fa is only enforced once enabled with a valid code
+	TwoFactorEnabled  bool   `json:"two_factor_enabled" gorm:"not null;default:false"`
+	TwoFactorSecret   string `json:"-" gorm:"not null;default:''"`
+	TwoFactorLastStep int64  `json:"-" gorm:"not null;default:0"`
+	// suspended accounts keep their data but cannot sign in
+	Suspended   bool       `json:"suspended" gorm:"not null;default:false"`
+	SuspendedAt *time.Time `json:"suspended_at"`
 	domain.BaseEntity
 }
 
 func (u *User) GetBaseEntity() *domain.BaseEntity {
 	return &u.BaseEntity
 }
+
+func (u *User) AuditType() string {
+	return "user"
+}
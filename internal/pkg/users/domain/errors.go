@@ -8,4 +8,22 @@ var (
 	ErrFailedToRenderHTML       = errors.New("failed to render HTML")
 	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
 	ErrUserNotFound             = errors.New("user not found")
+	ErrInvalidRefreshToken      = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reuse detected, session revoked")
+	ErrInvalidResetToken        = errors.New("invalid or expired password reset token")
+	ErrIncorrectPassword        = errors.New("current password is incorrect")
+	ErrEmailTaken               = errors.New("email is already in use")
+	ErrSameEmail                = errors.New("new email matches the current one")
+	ErrInvalidEmailChangeToken  = errors.New("invalid or expired email change token")
+	ErrAccountLocked            = errors.New("account is temporarily locked after too many failed logins")
+	ErrTooManyAttempts          = errors.New("too many failed logins from this address, try again later")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotSetUp        = errors.New("two-factor authentication has not been set up")
+	ErrInvalidTwoFactorCode     = errors.New("invalid two-factor code")
+	ErrInvalidMfaToken          = errors.New("invalid or expired mfa token")
+	ErrAccountSuspended         = errors.New("account is suspended")
+	ErrLastAdmin                = errors.New("cannot remove the last active admin")
+	ErrInvalidRole              = errors.New("invalid role")
+	ErrProductsOnOpenOrders     = errors.New("other customers have open orders on the user's products")
 )
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+// LoginThrottle counts failed logins of one account or client ip, Subject is the lowercased email or the ip
+type LoginThrottle struct {
+	Kind         string     `json:"kind" gorm:"not null;uniqueIndex:idx_login_throttle_subject"`
+	Subject      string     `json:"subject" gorm:"not null;uniqueIndex:idx_login_throttle_subject"`
+	FailedCount  int        `json:"failed_count" gorm:"not null;default:0"`
+	LastFailedAt time.Time  `json:"last_failed_at" gorm:"not null"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	domain.BaseEntity
+}
+
+func (t *LoginThrottle) GetBaseEntity() *domain.BaseEntity {
+	return &t.BaseEntity
+}
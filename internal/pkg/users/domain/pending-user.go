@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+// PendingUser holds a registration until its email is verified, the password is already hashed
+type PendingUser struct {
+	Email     string    `json:"email" gorm:"not null;index"`
+	Password  string    `json:"-" gorm:"not null"`
+	Username  string    `json:"username" gorm:"not null"`
+	Birthdate string    `json:"birthdate" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	domain.BaseEntity
+}
+
+func (p *PendingUser) GetBaseEntity() *domain.BaseEntity {
+	return &p.BaseEntity
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+// RecoveryCode is a single use 2fa fallback, only the hash of the normalized code is stored
+type RecoveryCode struct {
+	UserID   string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	CodeHash string     `json:"-" gorm:"not null;index"`
+	UsedAt   *time.Time `json:"used_at"`
+	domain.BaseEntity
+}
+
+func (r *RecoveryCode) GetBaseEntity() *domain.BaseEntity {
+	return &r.BaseEntity
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+// Session is one refresh token, rotated tokens share the FamilyID of the login that started them
+type Session struct {
+	UserID     string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	FamilyID   string     `json:"family_id" gorm:"type:uuid;not null;index"`
+	TokenID    string     `json:"token_id" gorm:"type:uuid;not null;uniqueIndex"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy *string    `json:"replaced_by" gorm:"type:uuid"`
+	domain.BaseEntity
+}
+
+func (s *Session) GetBaseEntity() *domain.BaseEntity {
+	return &s.BaseEntity
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/domain"
+)
+
+// UserToken is a single use token sent by email, only the sha256 hash of the token is stored
+type UserToken struct {
+	UserID    string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	Payload   string     `json:"-" gorm:"not null;default:''"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	domain.BaseEntity
+}
+
+func (t *UserToken) GetBaseEntity() *domain.BaseEntity {
+	return &t.BaseEntity
+}
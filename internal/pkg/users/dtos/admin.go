@@ -0,0 +1,9 @@
+package dtos
+
+type ChangeRoleDto struct {
+	Role string `json:"role" validate:"required,oneof=admin user"`
+}
+
+type SuspendUserDto struct {
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
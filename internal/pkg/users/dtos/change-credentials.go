@@ -0,0 +1,15 @@
+package dtos
+
+type ChangePasswordDto struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ChangeEmailDto struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ConfirmEmailChangeDto struct {
+	Token string `json:"token" validate:"required"`
+}
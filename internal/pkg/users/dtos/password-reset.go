@@ -0,0 +1,10 @@
+package dtos
+
+type ForgotPasswordDto struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordDto struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
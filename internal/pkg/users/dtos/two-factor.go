@@ -0,0 +1,15 @@
+package dtos
+
+type TwoFactorCodeDto struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorDto struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type VerifyTwoFactorDto struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
@@ -0,0 +1,8 @@
+package enums
+
+type ThrottleKind string
+
+const (
+	ThrottleAccount ThrottleKind = "account"
+	ThrottleIP      ThrottleKind = "ip"
+)
@@ -0,0 +1,8 @@
+package enums
+
+type TokenPurpose string
+
+const (
+	PasswordReset TokenPurpose = "password_reset"
+	EmailChange   TokenPurpose = "email_change"
+)
@@ -0,0 +1,25 @@
+package infrastructure
+
+import "github.com/QuangNV23062004/learning-go/internal/infrastructure"
+
+var userListSpec = infrastructure.ListSpec{
+	Sort: map[string]string{
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"username":   "username",
+		"email":      "email",
+	},
+	Search: map[string]infrastructure.FilterField{
+		"username": {Column: "username", Kind: infrastructure.FilterString},
+		"email":    {Column: "email", Kind: infrastructure.FilterString},
+	},
+	Filters: infrastructure.FilterFields{
+		"email":              {Column: "email", Kind: infrastructure.FilterString},
+		"username":           {Column: "username", Kind: infrastructure.FilterString},
+		"role":               {Column: "role", Kind: infrastructure.FilterString},
+		"suspended":          {Column: "suspended", Kind: infrastructure.FilterBool},
+		"two_factor_enabled": {Column: "two_factor_enabled", Kind: infrastructure.FilterBool},
+		"created_at":         {Column: "created_at", Kind: infrastructure.FilterTime},
+		"updated_at":         {Column: "updated_at", Kind: infrastructure.FilterTime},
+	},
+}
@@ -0,0 +1,86 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleRepository struct {
+	*infrastructure.BaseRepository[*domain.LoginThrottle]
+	db *gorm.DB
+}
+
+func NewLoginThrottleRepository(db *gorm.DB) *LoginThrottleRepository {
+	return &LoginThrottleRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.LoginThrottle](db),
+		db:             db,
+	}
+}
+
+// LockedUntil returns the end of the current lockout, nil when the subject is not locked
+func (r *LoginThrottleRepository) LockedUntil(kind string, subject string, tx *gorm.DB) (*time.Time, error) {
+	var throttles []domain.LoginThrottle
+	err := r.GetDatabase(tx).
+		Where("kind = ? AND subject = ? AND locked_until > ?", kind, subject, time.Now()).
+		Limit(1).
+		Find(&throttles).Error
+	if err != nil || len(throttles) == 0 {
+		return nil, err
+	}
+	return throttles[0].LockedUntil, nil
+}
+
+// RecordFailure counts a failed login, failures older than the window start the count over
+func (r *LoginThrottleRepository) RecordFailure(kind string, subject string, window time.Duration, tx *gorm.DB) (*domain.LoginThrottle, error) {
+	now := time.Now()
+	db := r.GetDatabase(tx)
+
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "kind"}, {Name: "subject"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failed_count":   gorm.Expr("CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failed_count + 1 END", now.Add(-window)),
+			"last_failed_at": now,
+			"updated_at":     now,
+			"version":        gorm.Expr("login_throttles.version + 1"),
+		}),
+	}).Create(&domain.LoginThrottle{
+		Kind:         kind,
+		Subject:      subject,
+		FailedCount:  1,
+		LastFailedAt: now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	throttle := &domain.LoginThrottle{}
+	if err := db.Where("kind = ? AND subject = ?", kind, subject).First(throttle).Error; err != nil {
+		return nil, err
+	}
+	return throttle, nil
+}
+
+func (r *LoginThrottleRepository) Lock(id string, until time.Time, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.LoginThrottle{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"locked_until": until,
+			"version":      gorm.Expr("version + 1"),
+		}).Error
+}
+
+// Reset forgets the failures of the subject, false when there were none
+func (r *LoginThrottleRepository) Reset(kind string, subject string, tx *gorm.DB) (bool, error) {
+	result := r.GetDatabase(tx).
+		Where("kind = ? AND subject = ?", kind, subject).
+		Delete(&domain.LoginThrottle{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
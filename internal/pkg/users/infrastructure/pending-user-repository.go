@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PendingUserRepository struct {
+	*infrastructure.BaseRepository[*domain.PendingUser]
+	db *gorm.DB
+}
+
+func NewPendingUserRepository(db *gorm.DB) *PendingUserRepository {
+	return &PendingUserRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.PendingUser](db),
+		db:             db,
+	}
+}
+
+// DeleteExpiredByEmail drops registrations of the email whose link ran out before now
+func (r *PendingUserRepository) DeleteExpiredByEmail(email string, now time.Time, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("email = ? AND expires_at <= ?", email, now).Delete(&domain.PendingUser{}).Error
+}
+
+// FindLiveByEmail locks the newest registration of the email that has not expired, nil when there is none
+func (r *PendingUserRepository) FindLiveByEmail(email string, now time.Time, tx *gorm.DB) (*domain.PendingUser, error) {
+	var pendingUsers []*domain.PendingUser
+	err := r.GetDatabase(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("email = ? AND expires_at > ?", email, now).
+		Order("created_at desc").
+		Limit(1).
+		Find(&pendingUsers).Error
+	if err != nil || len(pendingUsers) == 0 {
+		return nil, err
+	}
+	return pendingUsers[0], nil
+}
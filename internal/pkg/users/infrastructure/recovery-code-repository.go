@@ -0,0 +1,58 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository struct {
+	*infrastructure.BaseRepository[*domain.RecoveryCode]
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.RecoveryCode](db),
+		db:             db,
+	}
+}
+
+// ReplaceForUser drops every code of the user, used or not, and stores the new hashes
+func (r *RecoveryCodeRepository) ReplaceForUser(userID string, codeHashes []string, tx *gorm.DB) error {
+	db := r.GetDatabase(tx)
+	if err := r.DeleteForUser(userID, db); err != nil {
+		return err
+	}
+
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	codes := make([]domain.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return db.Create(&codes).Error
+}
+
+func (r *RecoveryCodeRepository) DeleteForUser(userID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+}
+
+// Consume marks the matching unused code as used, false when there is none
+func (r *RecoveryCodeRepository) Consume(userID string, codeHash string, tx *gorm.DB) (bool, error) {
+	result := r.GetDatabase(tx).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Updates(map[string]interface{}{
+			"used_at": time.Now(),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -3,8 +3,10 @@ package infrastructure
 import (
 	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
@@ -15,14 +17,37 @@ type UserRepository struct {
 // Constructor
 func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{
-		BaseRepository: infrastructure.NewBaseRepository[*domain.User](db),
+		BaseRepository: infrastructure.NewBaseRepository[*domain.User](db).WithListSpec(userListSpec),
 		db:             db,
 	}
 }
 
+// GetTokenVersion returns the token version of an active user, deleted and suspended users have none
+func (r *UserRepository) GetTokenVersion(userID string) (int, error) {
+	user := &domain.User{}
+	err := r.db.Model(&domain.User{}).
+		Where("id = ? AND is_deleted = ? AND suspended = ?", userID, false, false).
+		Select("token_version").
+		Take(user).Error
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+func (r *UserRepository) IncrementTokenVersion(userID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"token_version": gorm.Expr("token_version + 1"),
+			"version":       gorm.Expr("version + 1"),
+		}).Error
+}
+
+// FindByEmail matches the email case insensitively
 func (r *UserRepository) FindByEmail(email string, tx *gorm.DB) (*domain.User, error) {
 	user := &domain.User{}
-	if err := r.GetDatabase(tx).Model(&domain.User{}).Where("email = ?", email).First(user).Error; err != nil {
+	if err := r.GetDatabase(tx).Model(&domain.User{}).Where("LOWER(email) = LOWER(?)", email).First(user).Error; err != nil {
 		return nil, err
 	}
 
@@ -32,3 +57,41 @@ func (r *UserRepository) FindByEmail(email string, tx *gorm.DB) (*domain.User, e
 
 	return user, nil
 }
+
+// EmailTaken also counts deleted users, the unique index still holds their email. The email is
+// matched case insensitively like FindByEmail
+func (r *UserRepository) EmailTaken(email string, tx *gorm.DB) (bool, error) {
+	var count int64
+	if err := r.GetDatabase(tx).Model(&domain.User{}).Where("LOWER(email) = LOWER(?)", email).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AdvanceTwoFactorStep records the totp step as used, false when it or a later one was used already
+func (r *UserRepository) AdvanceTwoFactorStep(userID string, step int64, tx *gorm.DB) (bool, error) {
+	result := r.GetDatabase(tx).Model(&domain.User{}).
+		Where("id = ? AND two_factor_last_step < ?", userID, step).
+		Updates(map[string]interface{}{
+			"two_factor_last_step": step,
+			"version":              gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// LockActiveAdmins locks the rows of every admin who can still sign in, callers count them to keep at least one
+func (r *UserRepository) LockActiveAdmins(tx *gorm.DB) ([]string, error) {
+	var ids []string
+	err := r.GetDatabase(tx).Model(&domain.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND is_deleted = ? AND suspended = ?", string(enums.Admin), false, false).
+		Order("id").
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
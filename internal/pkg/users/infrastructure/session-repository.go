@@ -0,0 +1,67 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	*infrastructure.BaseRepository[*domain.Session]
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.Session](db),
+		db:             db,
+	}
+}
+
+func (r *SessionRepository) FindByTokenID(tokenID string, tx *gorm.DB) (*domain.Session, error) {
+	session := &domain.Session{}
+	if err := r.GetDatabase(tx).Where("token_id = ?", tokenID).First(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Revoke marks the token as used, false when it was already revoked so a reuse can be detected
+func (r *SessionRepository) Revoke(tokenID string, replacedBy *string, tx *gorm.DB) (bool, error) {
+	result := r.GetDatabase(tx).Model(&domain.Session{}).
+		Where("token_id = ? AND revoked_at IS NULL", tokenID).
+		Updates(map[string]interface{}{
+			"revoked_at":  time.Now(),
+			"replaced_by": replacedBy,
+			"version":     gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *SessionRepository) RevokeAllForUser(userID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *SessionRepository) RevokeFamily(familyID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{
+			"revoked_at": time.Now(),
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *SessionRepository) DeleteForUser(userID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("user_id = ?", userID).Delete(&domain.Session{}).Error
+}
@@ -0,0 +1,62 @@
+package infrastructure
+
+import (
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/infrastructure"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
+
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository struct {
+	*infrastructure.BaseRepository[*domain.UserToken]
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{
+		BaseRepository: infrastructure.NewBaseRepository[*domain.UserToken](db),
+		db:             db,
+	}
+}
+
+// FindUsable returns the unused, unexpired token with the given hash and purpose
+func (r *UserTokenRepository) FindUsable(tokenHash string, purpose string, tx *gorm.DB) (*domain.UserToken, error) {
+	token := &domain.UserToken{}
+	err := r.GetDatabase(tx).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, time.Now()).
+		First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Consume marks the token as used, false when another request used it first
+func (r *UserTokenRepository) Consume(id string, tx *gorm.DB) (bool, error) {
+	result := r.GetDatabase(tx).Model(&domain.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"used_at": time.Now(),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser uses up the outstanding tokens of a purpose so only the newest link works
+func (r *UserTokenRepository) InvalidateForUser(userID string, purpose string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Model(&domain.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Updates(map[string]interface{}{
+			"used_at": time.Now(),
+			"version": gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *UserTokenRepository) DeleteForUser(userID string, tx *gorm.DB) error {
+	return r.GetDatabase(tx).Where("user_id = ?", userID).Delete(&domain.UserToken{}).Error
+}
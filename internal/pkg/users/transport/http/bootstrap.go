@@ -2,6 +2,12 @@ package http
 
 import (
 	"github.com/QuangNV23062004/learning-go/internal/config"
+	auditInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/audit/infrastructure"
+	cartInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/carts/infrastructure"
+	orderInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/orders/infrastructure"
+	permissionApplication "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/application"
+	permissionInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/permissions/infrastructure"
+	productInfrastructure "github.com/QuangNV23062004/learning-go/internal/pkg/products/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/application"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/infrastructure"
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/templates"
@@ -11,13 +17,23 @@ import (
 	"gorm.io/gorm"
 )
 
-func BootstrapUserRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig, mailConfig *config.MailConfig, serverConfig *config.ServerConfig) {
+func BootstrapUserRoutes(api *fiber.App, db *gorm.DB, jwtConfig *config.JWTConfig, mailConfig *config.MailConfig, serverConfig *config.ServerConfig, authConfig *config.AuthConfig) {
 	jwtService := utils.NewJwtService(jwtConfig)
 	emailService := utils.NewEmailService(mailConfig, templates.TemplatesFS)
 	passwordService := utils.NewPasswordService()
 	userRepository := infrastructure.NewUserRepository(db)
-	userService := application.NewUserService(userRepository, jwtService, emailService, passwordService, serverConfig)
+	sessionRepository := infrastructure.NewSessionRepository(db)
+	pendingUserRepository := infrastructure.NewPendingUserRepository(db)
+	userTokenRepository := infrastructure.NewUserTokenRepository(db)
+	loginThrottleRepository := infrastructure.NewLoginThrottleRepository(db)
+	recoveryCodeRepository := infrastructure.NewRecoveryCodeRepository(db)
+	auditRepository := auditInfrastructure.NewAuditRepository(db)
+	permissionService := permissionApplication.NewPermissionService(permissionInfrastructure.NewPermissionRepository(db), auditRepository)
+	orderRepository := orderInfrastructure.NewOrderRepository(db)
+	productRepository := productInfrastructure.NewProductRepository(db)
+	cartRepository := cartInfrastructure.NewCartRepository(db)
+	userService := application.NewUserService(userRepository, sessionRepository, pendingUserRepository, userTokenRepository, loginThrottleRepository, recoveryCodeRepository, permissionService, orderRepository, productRepository, cartRepository, auditRepository, jwtService, emailService, passwordService, serverConfig, authConfig)
 	userHandler := NewUserHandler(userService)
-	userRouter := NewRouter(userHandler, jwtService)
+	userRouter := NewRouter(userHandler, jwtService, userRepository, permissionService)
 	userRouter.SetupRoutes(api)
 }
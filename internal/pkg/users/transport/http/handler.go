@@ -46,19 +46,42 @@ func (h *UserHandler) Login(c fiber.Ctx) error {
 		return err
 	}
 
-	credentials, err := h.service.Login(body)
+	credentials, err := h.service.Login(body, c.IP())
 	if err != nil {
 		return err
 	}
 
-	userJson, err := json.Marshal(credentials.User)
+	// no cookies until the second factor is verified
+	if credentials.MfaRequired {
+		return c.Status(fiber.StatusOK).JSON(utils.Success(
+			credentials, fiber.StatusOK,
+		))
+	}
+
+	if err := setAuthCookies(c, credentials); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		credentials, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) VerifyTwoFactor(c fiber.Ctx) error {
+	var body dtos.VerifyTwoFactorDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	credentials, err := h.service.VerifyTwoFactor(body, c.IP())
 	if err != nil {
 		return err
 	}
 
-	c.Cookies("accessToken", credentials.AccessToken)
-	c.Cookies("refreshToken", credentials.RefreshToken)
-	c.Cookies("user", string(userJson))
+	if err := setAuthCookies(c, credentials); err != nil {
+		return err
+	}
 
 	return c.Status(fiber.StatusOK).JSON(utils.Success(
 		credentials, fiber.StatusOK,
@@ -95,20 +118,94 @@ func (h *UserHandler) RefreshToken(c fiber.Ctx) error {
 		return err
 	}
 
-	userJson, err := json.Marshal(credentials.User)
+	if err := setAuthCookies(c, credentials); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		credentials, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) Logout(c fiber.Ctx) error {
+	if err := h.service.Logout(c.Cookies("refreshToken")); err != nil {
+		return err
+	}
+
+	clearAuthCookies(c)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		"Logged out", fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) LogoutAll(c fiber.Ctx) error {
+	sub, err := httpError.Subject(c)
 	if err != nil {
 		return err
 	}
+	if err := h.service.LogoutAll(sub); err != nil {
+		return err
+	}
 
-	c.Cookies("accessToken", credentials.AccessToken)
-	c.Cookies("refreshToken", credentials.RefreshToken)
-	c.Cookies("user", string(userJson))
+	clearAuthCookies(c)
 
 	return c.Status(fiber.StatusOK).JSON(utils.Success(
-		credentials, fiber.StatusOK,
+		"Logged out from all sessions", fiber.StatusOK,
 	))
 }
 
+func (h *UserHandler) ForgotPassword(c fiber.Ctx) error {
+	var body dtos.ForgotPasswordDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	if err := h.service.ForgotPassword(body); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		"If the email is registered, a reset link has been sent", fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) ResetPassword(c fiber.Ctx) error {
+	var body dtos.ResetPasswordDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	if err := h.service.ResetPassword(body); err != nil {
+		return err
+	}
+
+	clearAuthCookies(c)
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		"Password has been reset", fiber.StatusOK,
+	))
+}
+
+func setAuthCookies(c fiber.Ctx, credentials *application.UserCredentials) error {
+	userJson, err := json.Marshal(credentials.User)
+	if err != nil {
+		return err
+	}
+
+	c.Cookie(&fiber.Cookie{Name: "accessToken", Value: credentials.AccessToken, Path: "/", HTTPOnly: true})
+	c.Cookie(&fiber.Cookie{Name: "refreshToken", Value: credentials.RefreshToken, Path: "/", HTTPOnly: true})
+	c.Cookie(&fiber.Cookie{Name: "user", Value: string(userJson), Path: "/"})
+
+	return nil
+}
+
+func clearAuthCookies(c fiber.Ctx) {
+	c.ClearCookie("accessToken", "refreshToken", "user")
+}
+
 // Users
 func (h *UserHandler) GetAllUsers(c fiber.Ctx) error {
 	includeDeleted := c.Query("includeDeleted", "false") == "true"
@@ -124,7 +221,7 @@ func (h *UserHandler) GetAllUsers(c fiber.Ctx) error {
 func (h *UserHandler) GetUserByID(c fiber.Ctx) error {
 	id := c.Params("id")
 
-	role := c.Locals("role").(string)
+	role := httpError.Role(c)
 	includeDeleted := c.Query("includeDeleted", "false") == "true"
 	user, err := h.service.GetUserByID(id, role, includeDeleted)
 	if err != nil {
@@ -137,9 +234,16 @@ func (h *UserHandler) GetUserByID(c fiber.Ctx) error {
 
 func (h *UserHandler) DeleteUser(c fiber.Ctx) error {
 	id := c.Params("id")
-	role := c.Locals("role").(string)
-	sub := c.Locals("sub").(string)
-	deleted, err := h.service.DeleteUser(id, role, sub)
+	role := httpError.Role(c)
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+	deleted, err := h.service.DeleteUser(id, role, sub, version)
 	if err != nil {
 		return err
 	}
@@ -150,7 +254,15 @@ func (h *UserHandler) DeleteUser(c fiber.Ctx) error {
 
 func (h *UserHandler) RestoreUser(c fiber.Ctx) error {
 	id := c.Params("id")
-	restored, err := h.service.RestoreUser(id)
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	restored, err := h.service.RestoreUser(id, sub, version)
 	if err != nil {
 		return err
 	}
@@ -159,9 +271,115 @@ func (h *UserHandler) RestoreUser(c fiber.Ctx) error {
 	))
 }
 
+func (h *UserHandler) UnlockUser(c fiber.Ctx) error {
+	id := c.Params("id")
+	unlocked, err := h.service.UnlockUser(id)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		unlocked, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) ChangeRole(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.ChangeRoleDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	user, err := h.service.ChangeRole(id, body, sub, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		user, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) SuspendUser(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.SuspendUserDto
+	if len(c.Body()) > 0 {
+		if err := c.Bind().Body(&body); err != nil {
+			err := httpError.ErrInvalidBody
+			return err
+		}
+	}
+
+	user, err := h.service.SuspendUser(id, body, sub, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		user, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) ReactivateUser(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	user, err := h.service.ReactivateUser(id, sub, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		user, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) PurgeUser(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	purged, err := h.service.PurgeUser(id, sub, version)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		purged, fiber.StatusOK,
+	))
+}
+
 func (h *UserHandler) UpdateUser(c fiber.Ctx) error {
 	id := c.Params("id")
-	sub := c.Locals("sub").(string)
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
 	var body dtos.UpdateUserDto
 
 	if err := c.Bind().Body(&body); err != nil {
@@ -169,7 +387,12 @@ func (h *UserHandler) UpdateUser(c fiber.Ctx) error {
 		return err
 	}
 
-	updatedUser, err := h.service.UpdateUser(id, body, sub)
+	version, err := httpError.ParseIfMatch(c)
+	if err != nil {
+		return err
+	}
+
+	updatedUser, err := h.service.UpdateUser(id, body, sub, version)
 	if err != nil {
 		return err
 	}
@@ -178,6 +401,173 @@ func (h *UserHandler) UpdateUser(c fiber.Ctx) error {
 	))
 }
 
+func (h *UserHandler) ChangePassword(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.ChangePasswordDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	credentials, err := h.service.ChangePassword(id, body, sub)
+	if err != nil {
+		return err
+	}
+
+	if err := setAuthCookies(c, credentials); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		credentials, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) RequestEmailChange(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.ChangeEmailDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	if err := h.service.RequestEmailChange(id, body, sub); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(utils.Success(
+		"Confirmation sent to "+body.Email, fiber.StatusAccepted,
+	))
+}
+
+func (h *UserHandler) PreviewEmailChange(c fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		err := httpError.ErrUnauthorized
+		return err
+	}
+
+	preview, err := h.service.PreviewEmailChange(token)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		preview, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) ConfirmEmailChange(c fiber.Ctx) error {
+	var body dtos.ConfirmEmailChangeDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+	if body.Token == "" {
+		err := httpError.ErrUnauthorized
+		return err
+	}
+
+	user, err := h.service.ConfirmEmailChange(body.Token)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		user, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) SetupTwoFactor(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+
+	setup, err := h.service.SetupTwoFactor(id, sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		setup, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) EnableTwoFactor(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.TwoFactorCodeDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	codes, err := h.service.EnableTwoFactor(id, body, sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		fiber.Map{"recovery_codes": codes}, fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) DisableTwoFactor(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.DisableTwoFactorDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	if err := h.service.DisableTwoFactor(id, body, sub); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		"Two-factor authentication disabled", fiber.StatusOK,
+	))
+}
+
+func (h *UserHandler) RegenerateRecoveryCodes(c fiber.Ctx) error {
+	id := c.Params("id")
+	sub, err := httpError.Subject(c)
+	if err != nil {
+		return err
+	}
+	var body dtos.TwoFactorCodeDto
+	if err := c.Bind().Body(&body); err != nil {
+		err := httpError.ErrInvalidBody
+		return err
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(id, body, sub)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.Success(
+		fiber.Map{"recovery_codes": codes}, fiber.StatusOK,
+	))
+}
+
 func (h *UserHandler) PaginatedUsers(c fiber.Ctx) error {
 	var query dtos.PaginatedUsersQueryDto
 	if err := c.Bind().Query(&query); err != nil {
@@ -188,7 +578,12 @@ func (h *UserHandler) PaginatedUsers(c fiber.Ctx) error {
 	// Apply defaults
 	query.ApplyDefaults()
 
-	data, err := h.service.PaginatedUsers(query.Page, query.Limit, query.Search, query.SearchField, query.Order, query.SortBy, query.IncludeDeleted)
+	filters, err := httpError.ParseFilters(c)
+	if err != nil {
+		return err
+	}
+
+	data, err := h.service.PaginatedUsers(query.Page, query.Limit, query.Cursor, query.Search, query.SearchField, query.Order, query.SortBy, filters, query.IncludeDeleted)
 	if err != nil {
 		return err
 	}
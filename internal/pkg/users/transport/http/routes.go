@@ -2,21 +2,25 @@ package http
 
 import (
 	"github.com/QuangNV23062004/learning-go/internal/middlewares"
-	"github.com/QuangNV23062004/learning-go/internal/pkg/users/enums"
+	"github.com/QuangNV23062004/learning-go/internal/pkg/permissions/enums"
 	"github.com/QuangNV23062004/learning-go/internal/utils"
 
 	"github.com/gofiber/fiber/v3"
 )
 
 type Router struct {
-	handler    *UserHandler
-	jwtService *utils.JwtService
+	handler       *UserHandler
+	jwtService    *utils.JwtService
+	tokenVersions middlewares.TokenVersionProvider
+	permissions   middlewares.PermissionChecker
 }
 
-func NewRouter(handler *UserHandler, jwtService *utils.JwtService) *Router {
+func NewRouter(handler *UserHandler, jwtService *utils.JwtService, tokenVersions middlewares.TokenVersionProvider, permissions middlewares.PermissionChecker) *Router {
 	return &Router{
-		handler:    handler,
-		jwtService: jwtService,
+		handler:       handler,
+		jwtService:    jwtService,
+		tokenVersions: tokenVersions,
+		permissions:   permissions,
 	}
 }
 
@@ -29,34 +33,95 @@ func (r *Router) SetupRoutes(app fiber.Router) {
 
 	auth.Get("/verify", middlewares.MarkPublic(), r.handler.VerifyUser)
 
+	auth.Post("/2fa/verify", middlewares.MarkPublic(), r.handler.VerifyTwoFactor)
+
 	auth.Post("/refresh", middlewares.MarkPublic(), r.handler.RefreshToken)
 
+	auth.Post("/logout", middlewares.MarkPublic(), r.handler.Logout)
+
+	auth.Post("/forgot-password", middlewares.MarkPublic(), r.handler.ForgotPassword)
+
+	auth.Post("/reset-password", middlewares.MarkPublic(), r.handler.ResetPassword)
+
+	// the link only shows the pending change, the token is used by the post
+	auth.Get("/confirm-email", middlewares.MarkPublic(), r.handler.PreviewEmailChange)
+
+	auth.Post("/confirm-email", middlewares.MarkPublic(), r.handler.ConfirmEmailChange)
+
+	auth.Post("/logout-all",
+		middlewares.AuthMiddleware(r.jwtService, r.tokenVersions),
+		r.handler.LogoutAll)
+
 	user := app.Group("/users")
 
-	user.Use(middlewares.AuthMiddleware(r.jwtService))
+	user.Use(middlewares.AuthMiddleware(r.jwtService, r.tokenVersions))
 
 	user.Get("/",
-		middlewares.RoleMiddleware([]string{string(enums.Admin)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersList)),
 		r.handler.PaginatedUsers)
 
 	user.Get("/all",
-		middlewares.RoleMiddleware([]string{string(enums.Admin)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersList)),
 		r.handler.GetAllUsers)
 
 	user.Get("/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin),
-			string(enums.User)}), r.handler.GetUserByID)
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersRead)),
+		r.handler.GetUserByID)
 
 	user.Patch("/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
 		r.handler.UpdateUser)
 
 	user.Delete("/:id",
-		middlewares.RoleMiddleware([]string{string(enums.Admin), string(enums.User)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersDeleteOwn), string(enums.UsersDeleteAny)),
 		r.handler.DeleteUser)
 
+	user.Post("/:id/password",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
+		r.handler.ChangePassword)
+
+	user.Post("/:id/email",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
+		r.handler.RequestEmailChange)
+
+	user.Post("/:id/2fa/setup",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
+		r.handler.SetupTwoFactor)
+
+	user.Post("/:id/2fa/enable",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
+		r.handler.EnableTwoFactor)
+
+	user.Post("/:id/2fa/disable",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
+		r.handler.DisableTwoFactor)
+
+	user.Post("/:id/2fa/recovery-codes",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUpdateOwn)),
+		r.handler.RegenerateRecoveryCodes)
+
 	user.Post("/:id/restore",
-		middlewares.RoleMiddleware([]string{string(enums.Admin)}),
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersRestore)),
 		r.handler.RestoreUser)
 
+	user.Patch("/:id/role",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersChangeRole)),
+		r.handler.ChangeRole)
+
+	user.Post("/:id/suspend",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersSuspend)),
+		r.handler.SuspendUser)
+
+	user.Post("/:id/reactivate",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersSuspend)),
+		r.handler.ReactivateUser)
+
+	user.Delete("/:id/purge",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersPurge)),
+		r.handler.PurgeUser)
+
+	user.Post("/:id/unlock",
+		middlewares.PermissionMiddleware(r.permissions, string(enums.UsersUnlock)),
+		r.handler.UnlockUser)
+
 }
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage writes files below a directory on disk, the server serves that directory itself
+type LocalStorage struct {
+	dir       string
+	publicURL string
+}
+
+func NewLocalStorage(dir string, publicURL string) *LocalStorage {
+	return &LocalStorage{
+		dir:       dir,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// write next to the target and rename so readers never see half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+// path keeps the key inside the storage directory
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.dir, clean), nil
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/QuangNV23062004/learning-go/internal/config"
+)
+
+// S3Storage talks to any S3 compatible endpoint with path style urls and signature v4,
+// which is what MinIO expects out of the box
+type S3Storage struct {
+	endpoint  string
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	publicURL string
+	client    *http.Client
+}
+
+func NewS3Storage(storageConfig *config.StorageConfig) *S3Storage {
+	endpoint := strings.TrimRight(storageConfig.S3Endpoint, "/")
+	publicURL := strings.TrimRight(storageConfig.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = endpoint + "/" + storageConfig.S3Bucket
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    storageConfig.S3Region,
+		bucket:    storageConfig.S3Bucket,
+		accessKey: storageConfig.S3AccessKey,
+		secretKey: storageConfig.S3SecretKey,
+		publicURL: publicURL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.do(ctx, http.MethodPut, key, data, contentType)
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	return s.do(ctx, http.MethodDelete, key, nil, "")
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+func (s *S3Storage) do(ctx context.Context, method string, key string, body []byte, contentType string) error {
+	if key == "" || strings.Contains(key, "..") {
+		return ErrInvalidKey
+	}
+
+	objectPath := "/" + s.bucket + "/" + escapePath(key)
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint+objectPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, objectPath, body, time.Now().UTC())
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 && !(method == http.MethodDelete && res.StatusCode == http.StatusNotFound) {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("s3 %s %s: %s: %s", method, key, res.Status, message)
+	}
+	return nil
+}
+
+// sign adds an AWS signature v4 Authorization header, the payload is hashed rather than left unsigned
+func (s *S3Storage) sign(req *http.Request, objectPath string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	headerValues := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		signedHeaders = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+		headerValues["content-type"] = contentType
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headerValues[name]) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		objectPath,
+		"",
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, strings.Join(signedHeaders, ";"), signature,
+	))
+}
+
+// escapePath encodes every segment of the key the way signature v4 expects
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+	}
+	return strings.Join(segments, "/")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
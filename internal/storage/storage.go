@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/QuangNV23062004/learning-go/internal/config"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps uploaded files under a key such as "products/<id>/<file>", URL is where clients fetch them
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewStorage builds the driver picked by the config
+func NewStorage(storageConfig *config.StorageConfig) (Storage, error) {
+	switch storageConfig.Driver {
+	case "", "local":
+		return NewLocalStorage(storageConfig.LocalDir, storageConfig.PublicURL), nil
+	case "s3":
+		return NewS3Storage(storageConfig), nil
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", storageConfig.Driver)
+}
@@ -0,0 +1,9 @@
+package types
+
+// Filter is one field[op]=value condition from a list query, it is checked against the
+// repository whitelist before it reaches sql
+type Filter struct {
+	Field    string
+	Operator string
+	Value    string
+}
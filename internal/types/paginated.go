@@ -9,4 +9,5 @@ type Paginated[T any] struct {
 	SortBy      string
 	HasPrevious bool
 	HasNext     bool
+	NextCursor  string
 }
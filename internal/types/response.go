@@ -5,4 +5,5 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Details interface{} `json:"details,omitempty"`
 }
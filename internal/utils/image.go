@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+// decoding is skipped above this many pixels so a tiny file can't expand into gigabytes
+const maxImagePixels = 40_000_000
+
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+// ImageExtensions are the sniffed content types uploads may have
+var ImageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// SniffImage looks at the bytes rather than the file name or the client's content type
+func SniffImage(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := ImageExtensions[contentType]; !ok {
+		return "", ErrUnsupportedImage
+	}
+	return contentType, nil
+}
+
+// MakeThumbnail decodes the image and scales it down to fit a size x size box, the thumbnail is a
+// jpeg on a white background. The original width and height are returned too
+func MakeThumbnail(data []byte, size int) ([]byte, int, int, error) {
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+	if imageConfig.Width <= 0 || imageConfig.Height <= 0 || imageConfig.Width*imageConfig.Height > maxImagePixels {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, ErrUnsupportedImage
+	}
+
+	width, height := fitBox(imageConfig.Width, imageConfig.Height, size)
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(thumb, thumb.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(thumb, thumb.Bounds(), scaleDown(src, width, height), image.Point{}, draw.Over)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, thumb, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, 0, 0, err
+	}
+	return out.Bytes(), imageConfig.Width, imageConfig.Height, nil
+}
+
+// fitBox keeps the aspect ratio, images already smaller than the box keep their size
+func fitBox(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// scaleDown averages every source pixel that falls into a target pixel, good enough for shrinking
+func scaleDown(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(pixel.R)
+					g += uint64(pixel.G)
+					b += uint64(pixel.B)
+					a += uint64(pixel.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
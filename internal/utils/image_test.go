@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestFitBox(t *testing.T) {
+	tests := []struct {
+		width, height, size int
+		wantW, wantH        int
+	}{
+		{width: 100, height: 50, size: 200, wantW: 100, wantH: 50},
+		{width: 200, height: 200, size: 200, wantW: 200, wantH: 200},
+		{width: 800, height: 400, size: 200, wantW: 200, wantH: 100},
+		{width: 400, height: 800, size: 200, wantW: 100, wantH: 200},
+		{width: 1000, height: 1000, size: 200, wantW: 200, wantH: 200},
+		{width: 10000, height: 10, size: 200, wantW: 200, wantH: 1},
+		{width: 10, height: 10000, size: 200, wantW: 1, wantH: 200},
+	}
+
+	for _, tt := range tests {
+		width, height := fitBox(tt.width, tt.height, tt.size)
+		if width != tt.wantW || height != tt.wantH {
+			t.Errorf("fitBox(%d, %d, %d) = %d, %d, want %d, %d", tt.width, tt.height, tt.size, width, height, tt.wantW, tt.wantH)
+		}
+	}
+}
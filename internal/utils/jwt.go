@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
@@ -8,17 +9,16 @@ import (
 	"github.com/QuangNV23062004/learning-go/internal/pkg/users/domain"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type JwtService struct {
 	config *config.JWTConfig
 }
 
+// VerifyEmailClaims only carries the id of the pending registration, never its data
 type VerifyEmailClaims struct {
-	Email     string
-	Password  string
-	Username  string
-	Birthdate string
+	PendingID string
 	ExpiresAt time.Time
 }
 
@@ -40,22 +40,21 @@ func (j *JwtService) GetJwtToken(tokenString string, key string) (*jwt.Token, er
 	return token, nil
 }
 
-func (j *JwtService) PrepareAuthClaims(user *domain.User) jwt.MapClaims {
+func (j *JwtService) PrepareAuthClaims(user *domain.User, tokenID string) jwt.MapClaims {
 	claims := jwt.MapClaims{
 		"iss":  j.config.Issuer,
 		"sub":  user.ID,
 		"role": user.Role,
+		"jti":  tokenID,
+		"tv":   user.TokenVersion,
 	}
 	return claims
 }
 
-func (j *JwtService) PrepareVerifyClaims(user *domain.User) jwt.MapClaims {
+func (j *JwtService) PrepareVerifyClaims(pendingID string) jwt.MapClaims {
 	claims := jwt.MapClaims{
-		"iss":       j.config.Issuer,
-		"email":     user.Email,
-		"password":  user.Password,
-		"username":  user.Username,
-		"birthdate": user.Birthdate,
+		"iss": j.config.Issuer,
+		"pid": pendingID,
 	}
 
 	return claims
@@ -87,7 +86,7 @@ func (j *JwtService) ParseExp(exp string) int64 {
 
 func (j *JwtService) GenerateAccessToken(user *domain.User) (string, error) {
 
-	claims := j.PrepareAuthClaims(user)
+	claims := j.PrepareAuthClaims(user, uuid.NewString())
 
 	accessSecret := []byte(j.config.AccessSecret)
 	accessExpiry := j.config.AccessExpiry
@@ -101,22 +100,25 @@ func (j *JwtService) GenerateAccessToken(user *domain.User) (string, error) {
 
 }
 
-func (j *JwtService) GenerateRefreshToken(user *domain.User) (string, error) {
-	claims := j.PrepareAuthClaims(user)
+// GenerateRefreshToken signs a refresh token for the session identified by tokenID and returns when it expires
+func (j *JwtService) GenerateRefreshToken(user *domain.User, tokenID string) (string, time.Time, error) {
+	claims := j.PrepareAuthClaims(user, tokenID)
 
 	refreshSecret := []byte(j.config.RefreshSecret)
 	refreshExpiry := j.config.RefreshExpiry
 
 	refreshToken, err := j.GenerateTokens(claims, refreshSecret, refreshExpiry)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return refreshToken, nil
+	expiresAt := time.Unix(j.ParseExp(fmt.Sprintf("%v", claims["exp"])), 0)
+
+	return refreshToken, expiresAt, nil
 }
 
-func (j *JwtService) GenerateVerifyToken(user *domain.User) (string, string, error) {
-	claims := j.PrepareVerifyClaims(user)
+func (j *JwtService) GenerateVerifyToken(pendingID string) (string, string, error) {
+	claims := j.PrepareVerifyClaims(pendingID)
 
 	verifySecret := []byte(j.config.VerifySecret)
 	verifyExpiry := j.config.VerifyExpiry
@@ -128,6 +130,34 @@ func (j *JwtService) GenerateVerifyToken(user *domain.User) (string, string, err
 	return token, verifyExpiry, nil
 }
 
+// GenerateMfaToken proves the password step of a login, it is exchanged for real tokens after the 2fa code
+func (j *JwtService) GenerateMfaToken(userID string) (string, error) {
+	claims := jwt.MapClaims{
+		"iss": j.config.Issuer,
+		"sub": userID,
+		"typ": "mfa",
+	}
+
+	return j.GenerateTokens(claims, []byte(j.config.MfaSecret), j.config.MfaExpiry)
+}
+
+// VerifyMfaToken returns the user id carried by a valid mfa pending token
+func (j *JwtService) VerifyMfaToken(tokenString string) (string, error) {
+	token, err := j.GetJwtToken(tokenString, j.config.MfaSecret)
+	if err != nil {
+		return "", err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["typ"] != "mfa" {
+		return "", jwt.ErrTokenInvalidClaims
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return "", jwt.ErrTokenInvalidClaims
+	}
+	return sub, nil
+}
+
 func (j *JwtService) VerifyAccessToken(tokenString string) (jwt.MapClaims, error) {
 
 	token, err := j.GetJwtToken(tokenString, j.config.AccessSecret)
@@ -158,12 +188,11 @@ func (j *JwtService) VerifyVerificationToken(tokenString string) (VerifyEmailCla
 		return VerifyEmailClaims{}, err
 	}
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+		pendingID, _ := claims["pid"].(string)
+		exp, _ := claims["exp"].(float64)
 		return VerifyEmailClaims{
-			Email:     claims["email"].(string),
-			Password:  claims["password"].(string),
-			Username:  claims["username"].(string),
-			Birthdate: claims["birthdate"].(string),
-			ExpiresAt: time.Unix(int64(claims["exp"].(float64)), 0),
+			PendingID: pendingID,
+			ExpiresAt: time.Unix(int64(exp), 0),
 		}, nil
 	}
 	return VerifyEmailClaims{}, nil
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// the SHA1 secret of the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RFC 6238 appendix B, cut to the last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d is %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		step   int64
+		wantOK bool
+	}{
+		{name: "current step", step: current, wantOK: true},
+		{name: "previous step", step: current - 1, wantOK: true},
+		{name: "next step", step: current + 1, wantOK: true},
+		{name: "two steps old", step: current - 2, wantOK: false},
+		{name: "two steps ahead", step: current + 2, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfcSecret, totpCode(key, tt.step), now)
+			if ok != tt.wantOK {
+				t.Fatalf("accepted is %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.step {
+				t.Errorf("step is %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPRejects(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{name: "wrong code", secret: rfcSecret, code: "000000"},
+		{name: "too short", secret: rfcSecret, code: "81804"},
+		{name: "too long", secret: rfcSecret, code: "0081804"},
+		{name: "invalid secret", secret: "not base32!", code: "081804"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok {
+				t.Error("code was accepted")
+			}
+		})
+	}
+}
+
+// secrets are accepted the way users copy them, in lower case and with spaces around
+func TestValidateTOTPNormalizesSecret(t *testing.T) {
+	if _, ok := ValidateTOTP(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", "081804", time.Unix(1111111109, 0)); !ok {
+		t.Error("code was rejected")
+	}
+}